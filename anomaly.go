@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+)
+
+// anomalyDetector flags runs whose story volume deviates sharply from the
+// recent rolling average
+type anomalyDetector struct {
+	window     int     // number of past runs kept in the rolling history
+	minSamples int     // runs needed before alerts are raised
+	threshold  float64 // deviation, in standard deviations, that counts as anomalous
+}
+
+// defaultAnomalyDetector compares against the last 30 runs at 2 standard deviations
+var defaultAnomalyDetector = anomalyDetector{window: 30, minSamples: 7, threshold: 2}
+
+// check reports whether current is anomalous given history, along with the
+// history's mean and standard deviation
+func (d anomalyDetector) check(history []int, current int) (bool, float64, float64) {
+	if len(history) < d.minSamples {
+		return false, 0, 0
+	}
+	var sum float64
+	for _, n := range history {
+		sum += float64(n)
+	}
+	mean := sum / float64(len(history))
+
+	var variance float64
+	for _, n := range history {
+		variance += (float64(n) - mean) * (float64(n) - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(history)))
+
+	// Avoid alerting on every small change when the history is flat
+	return math.Abs(float64(current)-mean) > d.threshold*math.Max(stddev, 1), mean, stddev
+}
+
+// record appends current to the history, keeping only the rolling window
+func (d anomalyDetector) record(history []int, current int) []int {
+	history = append(history, current)
+	if len(history) > d.window {
+		history = history[len(history)-d.window:]
+	}
+	return history
+}
+
+// checkStoryVolume alerts when this run's story count is anomalous and then
+// records it in the state's rolling history
+func checkStoryVolume(state *State, storiesFetched int) {
+	d := defaultAnomalyDetector
+	anomalous, mean, stddev := d.check(state.StoryCounts, storiesFetched)
+	if anomalous {
+		message := fmt.Sprintf("⚠️ Unusual story volume: %d new stories this run (rolling average %.1f ± %.1f)", storiesFetched, mean, stddev)
+		log.Println(message)
+		if alertWebhook := os.Getenv("ALERT_SLACK_WEBHOOK_URL"); alertWebhook != "" {
+			if err := postToSlack(alertWebhook, message); err != nil {
+				log.Printf("Error posting volume alert to Slack: %v", err)
+			}
+		}
+	}
+	state.StoryCounts = d.record(state.StoryCounts, storiesFetched)
+}
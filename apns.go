@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// APNs provider API hosts
+const (
+	apnsProductionHost = "https://api.push.apple.com"
+	apnsSandboxHost    = "https://api.sandbox.push.apple.com"
+)
+
+// apnsBodyLimit keeps notification bodies to what a lock screen shows
+const apnsBodyLimit = 178
+
+// APNSSender pushes stories to one iOS device through Apple's HTTP/2
+// provider API, authenticating with a certificate
+type APNSSender struct {
+	client      *http.Client
+	host        string
+	bundleID    string
+	deviceToken string
+}
+
+// apnsPayload is the notification body
+type apnsPayload struct {
+	APS struct {
+		Alert struct {
+			Title string `json:"title"`
+			Body  string `json:"body,omitempty"`
+		} `json:"alert"`
+	} `json:"aps"`
+}
+
+// apnsSenderFromEnv builds a sender from APNS_CERT_FILE (a .p12 export
+// protected by APNS_CERT_PASSWORD, or an unencrypted PEM certificate and key),
+// APNS_DEVICE_TOKEN and APNS_BUNDLE_ID, using the sandbox unless
+// APNS_PRODUCTION=true. It returns nil when APNs isn't configured
+func apnsSenderFromEnv() (*APNSSender, error) {
+	certFile := os.Getenv("APNS_CERT_FILE")
+	deviceToken := os.Getenv("APNS_DEVICE_TOKEN")
+	bundleID := os.Getenv("APNS_BUNDLE_ID")
+	if certFile == "" && deviceToken == "" && bundleID == "" {
+		return nil, nil
+	}
+	if certFile == "" || deviceToken == "" || bundleID == "" {
+		return nil, errors.New("APNS_CERT_FILE, APNS_DEVICE_TOKEN and APNS_BUNDLE_ID must all be set")
+	}
+	cert, err := loadAPNSCertificate(certFile, os.Getenv("APNS_CERT_PASSWORD"))
+	if err != nil {
+		return nil, fmt.Errorf("APNS_CERT_FILE: %w", err)
+	}
+
+	host := apnsSandboxHost
+	if os.Getenv("APNS_PRODUCTION") == "true" {
+		host = apnsProductionHost
+	}
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+		// APNs only speaks HTTP/2, which a custom TLS config turns off by default
+		ForceAttemptHTTP2: true,
+	}
+	return &APNSSender{
+		client:      &http.Client{Transport: transport, Timeout: 15 * time.Second},
+		host:        host,
+		bundleID:    bundleID,
+		deviceToken: deviceToken,
+	}, nil
+}
+
+// loadAPNSCertificate reads the push certificate and its private key from
+// a .p12 export protected by password, or from an unencrypted PEM file
+// holding both
+func loadAPNSCertificate(path, password string) (tls.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	block, rest := pem.Decode(data)
+	if block == nil {
+		return decodePKCS12(data, password)
+	}
+	for ; block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == "ENCRYPTED PRIVATE KEY" || strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED") {
+			return tls.Certificate{}, errors.New("encrypted PEM keys aren't supported, use the .p12 export with APNS_CERT_PASSWORD")
+		}
+	}
+	return tls.X509KeyPair(data, data)
+}
+
+// newAPNSPayload builds the notification for a story, with its title as
+// the notification title and the start of its summary as the body
+func newAPNSPayload(story Story, summary string) apnsPayload {
+	var payload apnsPayload
+	payload.APS.Alert.Title = displayTitle(story)
+	if summary != story.Title {
+		payload.APS.Alert.Body = truncateRunes(summary, apnsBodyLimit)
+	}
+	return payload
+}
+
+// Send pushes a story to the device
+func (s *APNSSender) Send(story Story, summary string) error {
+	data, _ := json.Marshal(newAPNSPayload(story, summary))
+
+	return withRetry(DestinationAPNs, func() error {
+		req, err := http.NewRequest("POST", s.host+"/3/device/"+s.deviceToken, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("apns-topic", s.bundleID)
+		req.Header.Set("apns-push-type", "alert")
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return &NotifyError{Destination: "apns", Err: err}
+		}
+		defer resp.Body.Close()
+
+		// Apple explains failures with a JSON "reason", e.g. BadDeviceToken
+		if resp.StatusCode != http.StatusOK {
+			return notifyResponseError("apns", resp)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The fixtures in testdata/apns hold one self-signed certificate and key,
+// exported by openssl 3 with its default PBES2/AES encryption, with
+// -legacy (RC2 certificates, 3DES key), with 3DES throughout as Keychain
+// Access does, and as PEM
+func TestLoadAPNSCertificate(t *testing.T) {
+	for _, tc := range []struct {
+		file, password string
+		wantErr        string
+	}{
+		{file: "openssl3.p12", password: "secret"},
+		{file: "legacy.p12", password: "secret"},
+		{file: "3des.p12", password: "secret"},
+		{file: "nopassword.p12"},
+		{file: "combined.pem"},
+		{file: "openssl3.p12", password: "wrong", wantErr: "wrong password"},
+		{file: "legacy.p12", wantErr: "wrong password"},
+		{file: "encrypted.pem", password: "secret", wantErr: "encrypted PEM keys aren't supported"},
+	} {
+		cert, err := loadAPNSCertificate(filepath.Join("testdata", "apns", tc.file), tc.password)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("loadAPNSCertificate(%s, %q) error = %v, want %q", tc.file, tc.password, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("loadAPNSCertificate(%s, %q): %v", tc.file, tc.password, err)
+			continue
+		}
+		if len(cert.Certificate) != 1 || cert.PrivateKey == nil {
+			t.Errorf("loadAPNSCertificate(%s) = %d certificates, key %v; want the certificate and its key", tc.file, len(cert.Certificate), cert.PrivateKey)
+		}
+	}
+}
+
+func TestBERToDER(t *testing.T) {
+	// SEQUENCE (indefinite) { INTEGER 5, SEQUENCE (indefinite) { NULL } }
+	ber := []byte{0x30, 0x80, 0x02, 0x01, 0x05, 0x30, 0x80, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00}
+	want := []byte{0x30, 0x07, 0x02, 0x01, 0x05, 0x30, 0x02, 0x05, 0x00}
+	got, err := berToDER(ber)
+	if err != nil || !bytes.Equal(got, want) {
+		t.Errorf("berToDER() = % x, %v; want % x", got, err, want)
+	}
+	if _, err := berToDER(ber[:6]); err == nil {
+		t.Error("berToDER() accepted truncated input")
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMaxBackfillDays caps how long a backfill range may be
+	defaultMaxBackfillDays = 90
+	// backfillListingLimit is the most posts a Reddit listing returns at once
+	backfillListingLimit = 100
+	// backfillMaxPages stops paging where Reddit does, at about 1,000 posts
+	backfillMaxPages = 10
+	// defaultBackfillPerMonth caps the stories kept for each month
+	defaultBackfillPerMonth = 50
+	// backfillConcurrency is how many stories are summarized at once
+	backfillConcurrency = 4
+)
+
+// backfillRecord is one story in the backfill output
+type backfillRecord struct {
+	Title     string    `json:"title"`
+	Link      string    `json:"link"`
+	Author    string    `json:"author,omitempty"`
+	Score     int       `json:"score"`
+	Published time.Time `json:"published"`
+	Summary   string    `json:"summary"`
+}
+
+// parseBackfillRange parses --from and --to (YYYY-MM-DD, both inclusive) and
+// checks the range against MAX_BACKFILL_DAYS
+func parseBackfillRange(fromValue, toValue string, loc *time.Location) (time.Time, time.Time, error) {
+	from, err := time.ParseInLocation("2006-01-02", fromValue, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("--from: want YYYY-MM-DD, got %q", fromValue)
+	}
+	to, err := time.ParseInLocation("2006-01-02", toValue, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("--to: want YYYY-MM-DD, got %q", toValue)
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("--to %s is before --from %s", toValue, fromValue)
+	}
+	to = to.AddDate(0, 0, 1) // through the end of the --to day
+	if maxDays := envInt("MAX_BACKFILL_DAYS", defaultMaxBackfillDays); to.Sub(from) > time.Duration(maxDays)*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("range is longer than MAX_BACKFILL_DAYS (%d)", maxDays)
+	}
+	return from, to, nil
+}
+
+// backfillWindow picks the smallest Reddit top-listing window reaching back
+// to from. Reddit can't list an arbitrary past month, so older ranges come
+// from the year's top posts, filtered by publish time
+func backfillWindow(from time.Time) string {
+	if time.Since(from) <= 30*24*time.Hour {
+		return "month"
+	}
+	return "year"
+}
+
+// fetchBackfillStories pages through r/subreddit's top listing for window
+// with after= until Reddit runs out of posts or every month of [from, to)
+// has perMonth stories. The listing is ordered by score, so each month keeps
+// its perMonth top stories. It returns them and how many posts were listed
+func fetchBackfillStories(subreddit, window string, from, to time.Time, perMonth int) ([]Story, int, error) {
+	months := 0
+	for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location()); month.Before(to); month = month.AddDate(0, 1, 0) {
+		months++
+	}
+	perMonthCount := make(map[string]int)
+	full := 0
+
+	var stories []Story
+	listed := 0
+	after := ""
+	for page := 0; page < backfillMaxPages && full < months; page++ {
+		listingURL := fmt.Sprintf("%s/r/%s/top.json?t=%s&limit=%d", redditBaseURL(), url.PathEscape(subreddit), window, backfillListingLimit)
+		if after != "" {
+			listingURL += "&after=" + url.QueryEscape(after)
+		}
+		candidates, next, err := fetchRedditListingPage(listingURL)
+		if err != nil {
+			return nil, 0, err
+		}
+		listed += len(candidates)
+		for _, story := range candidates {
+			if story.Published.Before(from) || !story.Published.Before(to) {
+				continue
+			}
+			month := story.Published.In(from.Location()).Format("2006-01")
+			if perMonthCount[month] >= perMonth {
+				continue
+			}
+			perMonthCount[month]++
+			if perMonthCount[month] == perMonth {
+				full++
+			}
+			stories = append(stories, story)
+		}
+		if next == "" {
+			break
+		}
+		after = next
+	}
+	return stories, listed, nil
+}
+
+// runBackfill summarizes a subreddit's top stories published between --from
+// and --to and writes them as JSON Lines to BACKFILL_OUTPUT (default
+// stdout). Nothing is posted and no state is written
+func runBackfill(fromValue, toValue string, streamLogs bool) {
+	hfAPIKey := os.Getenv("HUGGINGFACE_API_KEY")
+	if hfAPIKey == "" {
+		log.Fatal("Missing HUGGINGFACE_API_KEY in environment")
+	}
+	loc, err := botLocation()
+	if err != nil {
+		log.Fatalf("Invalid TIMEZONE: %v", err)
+	}
+	from, to, err := parseBackfillRange(fromValue, toValue, loc)
+	if err != nil {
+		log.Fatalf("Invalid backfill range: %v", err)
+	}
+
+	subreddit := os.Getenv("BACKFILL_SUBREDDIT")
+	if subreddit == "" {
+		subreddit = "news"
+	}
+	window := backfillWindow(from)
+	perMonth := envInt("BACKFILL_MAX_PER_MONTH", defaultBackfillPerMonth)
+	if perMonth == 0 {
+		perMonth = defaultBackfillPerMonth
+	}
+	stories, listed, err := fetchBackfillStories(subreddit, window, from, to, perMonth)
+	if err != nil {
+		log.Fatalf("Failed to fetch stories: %v", err)
+	}
+	log.Printf("Backfilling %d of %d stories from r/%s's top of the %s (at most %d per month)", len(stories), listed, subreddit, window, perMonth)
+
+	// The same filters and summarizer settings as a regular run, less the
+	// store checks, since past days' stories were likely posted at the time
+	stories = selectStories(stories, selectionStages(time.Time{}, nil), nil)
+	if summaryInput, _, err = loadSummaryInputTemplate(); err != nil {
+		log.Fatalf("Invalid SUMMARY_INPUT_TEMPLATE: %v", err)
+	}
+	if _, _, err := checkSummarizationPermission(""); err != nil {
+		log.Fatalf("Failed to load prohibited domains: %v", err)
+	}
+	hfEndpoints = newEndpointPool(hfBaseURLs())
+	if os.Getenv("SAFETY_FILTER") == "true" {
+		contentFilter, err = newSafetyFilter(os.Getenv("SAFETY_BLOCKLIST_FILE"))
+		if err != nil {
+			log.Fatalf("Failed to load safety filter: %v", err)
+		}
+	}
+	defaultLength, lengthByRank, err := lengthSettingsFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid summary length settings: %v", err)
+	}
+
+	storyLogs := newRunLog(streamLogs)
+	summaries := make([]string, len(stories))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, backfillConcurrency)
+	for i, story := range stories {
+		wg.Add(1)
+		go func(i int, s Story) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			length := lengthByRank.forRank(i+1, defaultLength)
+			summaries[i] = processStory(s, hfAPIKey, length, storyLogs.story(s))
+		}(i, story)
+	}
+	wg.Wait()
+	storyLogs.flush()
+
+	var out io.Writer = os.Stdout
+	if path := os.Getenv("BACKFILL_OUTPUT"); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			log.Fatalf("Error creating backfill output: %v", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	written := 0
+	for i, story := range stories {
+		if summaries[i] == "" {
+			continue
+		}
+		record := backfillRecord{
+			Title:     story.Title,
+			Link:      story.Link,
+			Author:    story.Author,
+			Score:     story.Score,
+			Published: story.Published,
+			Summary:   summaries[i],
+		}
+		if err := enc.Encode(record); err != nil {
+			log.Fatalf("Error writing backfill output: %v", err)
+		}
+		written++
+	}
+	log.Printf("Wrote %d backfilled stories", written)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// redditPost is one post in a stand-in Reddit listing page
+type redditPost struct {
+	Data struct {
+		Title      string  `json:"title"`
+		URL        string  `json:"url"`
+		Score      int     `json:"score"`
+		CreatedUTC float64 `json:"created_utc"`
+	} `json:"data"`
+}
+
+func TestFetchBackfillStories(t *testing.T) {
+	// A year's top listing: three pages of ten posts, by descending score,
+	// alternating between March and April with a few from February
+	var posts []redditPost
+	for i := 0; i < 30; i++ {
+		published := time.Date(2026, time.March+time.Month(i%2), 1+i/2, 12, 0, 0, 0, time.UTC)
+		if i%7 == 6 {
+			published = time.Date(2026, time.February, 10, 12, 0, 0, 0, time.UTC)
+		}
+		var post redditPost
+		post.Data.Title = fmt.Sprintf("Story %d", i)
+		post.Data.URL = fmt.Sprintf("https://example.com/%d", i)
+		post.Data.Score = 10000 - i
+		post.Data.CreatedUTC = float64(published.Unix())
+		posts = append(posts, post)
+	}
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+		start := 0
+		if after := r.URL.Query().Get("after"); after != "" {
+			start, _ = strconv.Atoi(after)
+		}
+		var page struct {
+			Data struct {
+				Children []redditPost `json:"children"`
+				After    string       `json:"after"`
+			} `json:"data"`
+		}
+		page.Data.Children = posts[start:min(start+10, len(posts))]
+		if start+10 < len(posts) {
+			page.Data.After = strconv.Itoa(start + 10)
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+	t.Setenv("REDDIT_BASE_URL", server.URL)
+
+	from := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC)
+
+	// Every page is needed to find 12 stories in each month
+	stories, listed, err := fetchBackfillStories("news", "year", from, to, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listed != 30 || len(requests) != 3 {
+		t.Fatalf("listed %d posts in %d requests, want 30 in 3", listed, len(requests))
+	}
+	if requests[1] != "t=year&limit=100&after=10" {
+		t.Errorf("second request = %q, want the after= cursor from the first page", requests[1])
+	}
+	perMonth := map[time.Month]int{}
+	for _, story := range stories {
+		perMonth[story.Published.UTC().Month()]++
+	}
+	if perMonth[time.February] != 0 || perMonth[time.March] != 12 || perMonth[time.April] != 12 {
+		t.Errorf("stories per month = %v, want 12 in March and April and none outside the range", perMonth)
+	}
+
+	// Once every month is full, paging stops
+	requests = nil
+	stories, _, err = fetchBackfillStories("news", "year", from, to, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stories) != 6 || len(requests) != 1 {
+		t.Errorf("got %d stories in %d requests, want 6 in 1", len(stories), len(requests))
+	}
+	if stories[0].Title != "Story 0" || stories[1].Title != "Story 1" {
+		t.Errorf("first stories = %q, %q; want the top scoring ones", stories[0].Title, stories[1].Title)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxSummaryCandidates caps SUMMARY_CANDIDATES, since each candidate is
+	// another beam the model has to search
+	maxSummaryCandidates = 5
+	// maxTitleSimilarity is the content-word overlap with the title above
+	// which a candidate is treated as a restatement of it
+	maxTitleSimilarity = 0.8
+)
+
+// stopWords are left out when comparing content words
+var stopWords = map[string]bool{
+	"about": true, "after": true, "also": true, "been": true, "before": true,
+	"from": true, "have": true, "into": true, "more": true, "over": true,
+	"said": true, "says": true, "some": true, "than": true, "that": true,
+	"their": true, "them": true, "then": true, "there": true, "they": true,
+	"this": true, "were": true, "what": true, "when": true, "which": true,
+	"while": true, "will": true, "with": true, "would": true,
+}
+
+// summaryCandidates reads SUMMARY_CANDIDATES, the number of summaries to
+// request per story (default 1)
+func summaryCandidates() int {
+	n := envInt("SUMMARY_CANDIDATES", 1)
+	if n < 1 {
+		return 1
+	}
+	return min(n, maxSummaryCandidates)
+}
+
+// contentWords returns the distinct lowercase words of text, ignoring short
+// words and stop words
+func contentWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), isWordBreak) {
+		if len(word) > 3 && !stopWords[word] {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+// isWordBreak reports whether r separates words
+func isWordBreak(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// overlap returns the fraction of a's words that also appear in b
+func overlap(a, b map[string]bool) float64 {
+	if len(a) == 0 {
+		return 0
+	}
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a))
+}
+
+// withinLength reports whether summary roughly fits the target length,
+// converting words to model tokens. The zero length accepts anything
+func withinLength(summary string, length SummaryLength) bool {
+	if length.MaxLength == 0 {
+		return true
+	}
+	tokens := int(float64(len(strings.Fields(summary))) / wordsPerToken)
+	return tokens >= length.MinLength && tokens <= length.MaxLength
+}
+
+// pickSummary chooses among candidate summaries of text: the one with the
+// highest content-word overlap with text, preferring candidates that don't
+// just restate the title and that fit the target length. It returns the
+// chosen index and why it won
+func pickSummary(candidates []string, text, title string, length SummaryLength) (int, string) {
+	textWords, titleWords := contentWords(text), contentWords(title)
+	best, bestOverlap, bestEligible := 0, -1.0, false
+	for i, candidate := range candidates {
+		words := contentWords(candidate)
+		eligible := overlap(words, titleWords) <= maxTitleSimilarity && withinLength(candidate, length)
+		score := overlap(words, textWords)
+		if (eligible && !bestEligible) || (eligible == bestEligible && score > bestOverlap) {
+			best, bestOverlap, bestEligible = i, score, eligible
+		}
+	}
+	reason := fmt.Sprintf("highest article overlap (%.2f)", bestOverlap)
+	if !bestEligible {
+		reason += ", though every candidate restates the title or misses the target length"
+	}
+	return best, reason
+}
+
+// summarizeBest requests count candidate summaries of text and returns the
+// best one by pickSummary, logging which won. Texts too long for one model
+// input fall back to a single chunked summary
+func summarizeBest(apiKey, text, title string, length SummaryLength, count int, logs *storyLog) (string, error) {
+	if len(chunkText(text, bartMaxTokens, chunkOverlapTokens)) > 1 {
+		return summarizeText(apiKey, text, length)
+	}
+	candidates, err := summarizeCandidates(apiKey, text, length, count)
+	if err != nil {
+		return "", err
+	}
+	best, reason := pickSummary(candidates, text, title, length)
+	logs.Printf("Chose summary candidate %d of %d: %s", best+1, len(candidates), reason)
+	return candidates[best], nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPickSummary(t *testing.T) {
+	const text = "The city council approved funding for new bicycle lanes downtown after months of debate among residents"
+	short := summaryLengthPresets["short"]
+
+	tests := []struct {
+		name       string
+		candidates []string
+		title      string
+		length     SummaryLength
+		want       int
+		wantReason string
+	}{
+		{
+			name: "highest article overlap wins",
+			candidates: []string{
+				"Residents debated for months.",
+				"The city council approved funding for bicycle lanes downtown.",
+			},
+			title:      "Council backs bike lanes",
+			want:       1,
+			wantReason: "highest article overlap (1.00)",
+		},
+		{
+			name: "restating the title loses to a lower overlap",
+			candidates: []string{
+				"City council approves bicycle lanes downtown.",
+				"Residents debated the plan for months.",
+			},
+			title:      "City council approves bicycle lanes downtown",
+			want:       1,
+			wantReason: "highest article overlap (0.50)",
+		},
+		{
+			name: "too long for the target length",
+			candidates: []string{
+				strings.Repeat("The city council approved funding for bicycle lanes downtown. ", 4),
+				"After months of debate among residents, the city council approved funding for the lanes plan.",
+			},
+			title:      "Council backs bike lanes",
+			length:     short,
+			want:       1,
+			wantReason: "highest article overlap (0.90)",
+		},
+		{
+			name: "every candidate restates the title",
+			candidates: []string{
+				"City council approves bicycle lanes.",
+				"City council approved bicycle lanes downtown.",
+			},
+			title:      "City council approves bicycle lanes downtown",
+			want:       1,
+			wantReason: "highest article overlap (1.00), though every candidate restates the title or misses the target length",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := pickSummary(tt.candidates, text, tt.title, tt.length)
+			if got != tt.want || reason != tt.wantReason {
+				t.Errorf("pickSummary() = %d, %q; want %d, %q", got, reason, tt.want, tt.wantReason)
+			}
+		})
+	}
+}
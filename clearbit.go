@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// clearbitCompanyURL is Clearbit's company lookup by domain
+const clearbitCompanyURL = "https://company.clearbit.com/v2/companies/find"
+
+// ClearbitCompany is the part of a Clearbit company record the bot uses
+type ClearbitCompany struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Logo        string `json:"logo"`
+}
+
+// enrichWithClearbit looks up the company that owns domain. A domain with no
+// known company returns a zero ClearbitCompany and no error
+func enrichWithClearbit(domain, apiKey string) (ClearbitCompany, error) {
+	var company ClearbitCompany
+	req, err := http.NewRequest("GET", clearbitCompanyURL+"?domain="+url.QueryEscape(domain), nil)
+	if err != nil {
+		return company, err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return company, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		err = json.NewDecoder(resp.Body).Decode(&company)
+		return company, err
+	case http.StatusAccepted, http.StatusNotFound:
+		// 202 means Clearbit is still looking the domain up; either way
+		// there's nothing to show for this run
+		return company, nil
+	default:
+		return company, &statusError{Service: "Clearbit", StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+}
+
+// enrichStories attaches company info to stories whose article domain belongs
+// to a company, looking each domain up once
+func enrichStories(stories []Story, apiKey string) {
+	companies := make(map[string]*ClearbitCompany)
+	for i := range stories {
+		domain := storyDomain(articleLink(stories[i]))
+		if domain == "" || domain == "reddit.com" || domain == "redd.it" {
+			continue
+		}
+		company, looked := companies[domain]
+		if !looked {
+			found, err := enrichWithClearbit(domain, apiKey)
+			if err != nil {
+				log.Printf("Error looking up %s on Clearbit: %v", domain, err)
+			}
+			if found.Name != "" {
+				company = &found
+			}
+			companies[domain] = company
+		}
+		stories[i].CompanyInfo = company
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// configKeys lists the environment variables that make up the bot's
+// effective configuration
+var configKeys = []string{
+	"BOT_ENV",
+	"SLACK_WEBHOOK_URL",
+	"HUGGINGFACE_API_KEY",
+	"HF_BASE_URLS",
+	"SLACK_MAX_POSTS_PER_RUN",
+	"SLACK_MIN_INTER_POST_DELAY_MS",
+	"RUN_SUMMARY",
+	"MAX_RETRY_ATTEMPTS",
+	"DEAD_LETTER_FILE",
+	"DEAD_LETTER_ALERT_THRESHOLD",
+	"ALERT_WEBHOOK_URL",
+	"SUMMARY_WEBHOOK_URL",
+	"WEBHOOK_JWT_SECRET",
+	"WEBHOOK_JWT_TTL_SECONDS",
+	"DATE_LOCALE",
+	"TIMEZONE",
+	"POST_WINDOW_START",
+	"POST_WINDOW_END",
+	"POST_WAIT_FOR_WINDOW",
+	"POST_WINDOW_MAX_HOLD_HOURS",
+	"SLACK_QUIET_HOURS",
+	"ZULIP_QUIET_HOURS",
+	"DISCORD_QUIET_HOURS",
+	"QUIET_HOURS_MAX_AGE_HOURS",
+	"SLACK_BOT_TOKEN",
+	"SLACK_CHANNEL_ID",
+	"SLACK_PIN_TOP_STORY",
+	"ZULIP_BOT_EMAIL",
+	"ZULIP_API_KEY",
+	"ZULIP_REALM",
+	"ZULIP_STREAM",
+	"ZULIP_TOPIC",
+	"DISCORD_WEBHOOK_URL",
+	"DISCORD_EMBED_COLOR",
+	"APNS_CERT_FILE",
+	"APNS_CERT_PASSWORD",
+	"APNS_DEVICE_TOKEN",
+	"APNS_BUNDLE_ID",
+	"APNS_PRODUCTION",
+	"FEED_URL",
+	"REDDIT_BASE_URL",
+	"FEED_FORMAT",
+	"USER_AGENTS_FILE",
+	"REDDIT_SEARCH_QUERY",
+	"REDDIT_SEARCH_SUBREDDIT",
+	"REDDIT_SEARCH_SORT",
+	"TRENDING_SUBREDDIT",
+	"VELOCITY_ALERT_THRESHOLD",
+	"SKIP_IMAGE_POSTS",
+	"TITLE_DEDUP_THRESHOLD",
+	"PRIORITY_DOMAINS",
+	"CLEARBIT_API_KEY",
+	"SNAPSHOT_DIR",
+	"SNAPSHOT_MAX_AGE_DAYS",
+	"SNAPSHOT_MAX_COUNT",
+	"BACKFILL_SUBREDDIT",
+	"BACKFILL_OUTPUT",
+	"MAX_BACKFILL_DAYS",
+	"BACKFILL_MAX_PER_MONTH",
+	"STATE_FILE",
+	"PIN_EXPIRY_HOURS",
+	"REDIS_URL",
+	"REDIS_TTL_HOURS",
+	"STORY_REPOST_COOLDOWN_DAYS",
+	"SPAM_MAX_POSTS_PER_AUTHOR_PER_DAY",
+	"SUMMARY_LIMIT_MIN",
+	"SUMMARY_LIMIT_MAX",
+	"SUMMARY_LIMIT_MIN_SCORE",
+	"SUMMARY_LENGTH",
+	"SUMMARY_LENGTH_POLICY",
+	"SUMMARY_CANDIDATES",
+	"SUMMARY_INPUT_TEMPLATE",
+	"FAST_EXTRACTIVE_FALLBACK",
+	"EXTRACTIVE_SENTENCES",
+	"TITLE_PASSTHROUGH_EASE",
+	"HTML_OUTPUT_DIR",
+	"HTML_TEMPLATE_DIR",
+	"SAFETY_FILTER",
+	"SAFETY_BLOCKLIST_FILE",
+	"PROHIBITED_DOMAINS_FILE",
+	"ALERT_SLACK_WEBHOOK_URL",
+	"CONFIG_CHANGE_NOTIFY",
+}
+
+// secretMarkers identify config keys whose values must never be logged
+var secretMarkers = []string{"KEY", "TOKEN", "SECRET", "PASSWORD", "WEBHOOK", "REDIS_URL"}
+
+// isSecretKey reports whether a config key holds a credential
+func isSecretKey(key string) bool {
+	for _, marker := range secretMarkers {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// configSnapshot captures the effective configuration for this run. Secret
+// values are replaced by a short fingerprint so changes can still be detected
+func configSnapshot(flags map[string]string) map[string]string {
+	snapshot := make(map[string]string)
+	for _, key := range configKeys {
+		value := os.Getenv(key)
+		if value != "" && isSecretKey(key) {
+			sum := sha256.Sum256([]byte(value))
+			value = "sha256:" + hex.EncodeToString(sum[:4])
+		}
+		snapshot[key] = value
+	}
+	for name, value := range flags {
+		snapshot["--"+name] = value
+	}
+	snapshot["summary_limit"] = fmt.Sprint(summaryLimit)
+	return snapshot
+}
+
+// hashConfig returns a stable hash of a config snapshot
+func hashConfig(snapshot map[string]string) string {
+	keys := sortedKeys(snapshot)
+	h := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s=%s\n", key, snapshot[key])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// diffConfig describes each key that differs between two snapshots
+func diffConfig(previous, current map[string]string) []string {
+	merged := make(map[string]string)
+	for key := range previous {
+		merged[key] = ""
+	}
+	for key := range current {
+		merged[key] = ""
+	}
+
+	var changes []string
+	for _, key := range sortedKeys(merged) {
+		before, hadBefore := previous[key]
+		after, hasAfter := current[key]
+		if before == after && hadBefore == hasAfter {
+			continue
+		}
+		switch {
+		case isSecretKey(key):
+			changes = append(changes, key+" changed")
+		case before == "":
+			changes = append(changes, fmt.Sprintf("%s set to %q", key, after))
+		case after == "":
+			changes = append(changes, key+" unset")
+		default:
+			changes = append(changes, fmt.Sprintf("%s %s→%s", key, before, after))
+		}
+	}
+	return changes
+}
+
+// checkConfigChange logs (and optionally posts) what changed in the
+// configuration since the previous run, then records the current snapshot
+func checkConfigChange(state *State, snapshot map[string]string) {
+	hash := hashConfig(snapshot)
+	if state.ConfigHash != "" && state.ConfigHash != hash {
+		changes := diffConfig(state.Config, snapshot)
+		for _, change := range changes {
+			log.Printf("config_change: %s", change)
+		}
+		alertWebhook := os.Getenv("ALERT_SLACK_WEBHOOK_URL")
+		if os.Getenv("CONFIG_CHANGE_NOTIFY") == "true" && alertWebhook != "" {
+			message := "Config changed since last run: " + strings.Join(changes, ", ")
+			if err := postToSlack(alertWebhook, message); err != nil {
+				log.Printf("Error posting config change to Slack: %v", err)
+			}
+		}
+	}
+	state.ConfigHash = hash
+	state.Config = snapshot
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,35 @@
+//go:build crossbuild
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// crossBuildTargets are the platforms the bot must build for without a C
+// toolchain, since it runs on a Raspberry Pi among other places
+var crossBuildTargets = []string{
+	"linux/amd64",
+	"linux/arm64",
+	"windows/amd64",
+}
+
+// TestCrossBuild builds the bot for each target with cgo disabled. Run it
+// with `go test -tags crossbuild -run TestCrossBuild .`
+func TestCrossBuild(t *testing.T) {
+	for _, target := range crossBuildTargets {
+		goos, goarch, _ := strings.Cut(target, "/")
+		t.Run(goos+"_"+goarch, func(t *testing.T) {
+			t.Parallel()
+			cmd := exec.Command("go", "build", "-o", filepath.Join(t.TempDir(), "bot"), ".")
+			cmd.Env = append(os.Environ(), "CGO_ENABLED=0", "GOOS="+goos, "GOARCH="+goarch)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("go build for %s: %v\n%s", target, err, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// FailedPost is a story whose Slack post failed, kept in the state file so
+// the next run tries it again
+type FailedPost struct {
+	Story     Story     `json:"story"`
+	Summary   string    `json:"summary"`
+	Attempts  int       `json:"attempts"` // runs in a row that failed to post it
+	LastError string    `json:"last_error"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+// failedAsPending turns failed posts back into posts for this run
+func failedAsPending(failed []FailedPost) []PendingPost {
+	pending := make([]PendingPost, len(failed))
+	for i, p := range failed {
+		pending[i] = PendingPost{Story: p.Story, Summary: p.Summary}
+	}
+	return pending
+}
+
+// deadLetterQueue decides what happens to a run's failed Slack posts: each
+// is retried on the next run until it has failed MAX_RETRY_ATTEMPTS runs
+// (default 3), then dead-lettered
+type deadLetterQueue struct {
+	retried     map[string]FailedPost // posts retried in this run, by link
+	maxAttempts int
+	dead        []FailedPost
+}
+
+// newDeadLetterQueue starts a run's queue from the posts retried in it
+func newDeadLetterQueue(retried []FailedPost) *deadLetterQueue {
+	q := &deadLetterQueue{
+		retried:     make(map[string]FailedPost),
+		maxAttempts: envInt("MAX_RETRY_ATTEMPTS", 3),
+	}
+	for _, p := range retried {
+		q.retried[p.Story.Link] = p
+	}
+	return q
+}
+
+// retrying reports whether link is a failed post being retried. Retries are
+// only owed to Slack, since the other destinations got them the first time
+func (q *deadLetterQueue) retrying(link string) bool {
+	_, ok := q.retried[link]
+	return ok
+}
+
+// keep puts a retried post that wasn't attempted this run back in the state
+// unchanged, so it keeps its attempts
+func (q *deadLetterQueue) keep(state *State, link string) {
+	if p, ok := q.retried[link]; ok {
+		state.FailedPosts = append(state.FailedPosts, p)
+	}
+}
+
+// fail records a failed post, either in the state for the next run or as
+// dead once it has used up its attempts
+func (q *deadLetterQueue) fail(state *State, story Story, summary string, err error) {
+	p := FailedPost{
+		Story:     story,
+		Summary:   summary,
+		Attempts:  q.retried[story.Link].Attempts + 1,
+		LastError: err.Error(),
+		FailedAt:  time.Now(),
+	}
+	if p.Attempts >= q.maxAttempts {
+		q.dead = append(q.dead, p)
+		return
+	}
+	state.FailedPosts = append(state.FailedPosts, p)
+}
+
+// flush appends the run's dead posts to DEAD_LETTER_FILE as JSON Lines and
+// alerts ALERT_WEBHOOK_URL (ALERT_SLACK_WEBHOOK_URL if unset) when more than
+// DEAD_LETTER_ALERT_THRESHOLD (default 3) were dead-lettered
+func (q *deadLetterQueue) flush() {
+	if len(q.dead) == 0 {
+		return
+	}
+	path := os.Getenv("DEAD_LETTER_FILE")
+	if path == "" {
+		for _, p := range q.dead {
+			log.Printf("Giving up on story after %d failed runs (set DEAD_LETTER_FILE to keep it): %s", p.Attempts, p.Story.Title)
+		}
+	} else if err := appendDeadLetters(path, q.dead); err != nil {
+		log.Printf("Error writing dead letters to %s: %v", path, err)
+	} else {
+		log.Printf("Dead-lettered %d stories to %s", len(q.dead), path)
+	}
+
+	if threshold := envInt("DEAD_LETTER_ALERT_THRESHOLD", 3); len(q.dead) > threshold {
+		message := fmt.Sprintf("⚠️ %d stories failed to post to Slack %d runs in a row and were dead-lettered", len(q.dead), q.maxAttempts)
+		if path != "" {
+			message += " to " + path
+		}
+		alertWebhook := os.Getenv("ALERT_WEBHOOK_URL")
+		if alertWebhook == "" {
+			alertWebhook = os.Getenv("ALERT_SLACK_WEBHOOK_URL")
+		}
+		if alertWebhook != "" {
+			if err := postToSlack(alertWebhook, message); err != nil {
+				log.Printf("Error posting dead letter alert: %v", err)
+			}
+		}
+	}
+}
+
+// appendDeadLetters adds posts to the dead letter file, one JSON object per line
+func appendDeadLetters(path string, posts []FailedPost) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, p := range posts {
+		if err := enc.Encode(p); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// requeuable drops requeued posts that are already waiting for a retry or
+// requeued twice, and ones the store shows were posted after they were
+// dead-lettered
+func requeuable(requeued, retried []FailedPost, store Store) []FailedPost {
+	queued := make(map[string]bool)
+	for _, p := range retried {
+		queued[p.Story.Link] = true
+	}
+	var kept []FailedPost
+	for _, p := range requeued {
+		if queued[p.Story.Link] {
+			log.Printf("Not requeuing %s: already queued for a retry", p.Story.Link)
+			continue
+		}
+		if store != nil {
+			postedAt, ok, err := store.LastPostedAt(p.Story.Link)
+			if err != nil {
+				log.Printf("Error checking store for requeued story %s: %v", p.Story.Link, err)
+			} else if ok && postedAt.After(p.FailedAt) {
+				log.Printf("Not requeuing %s: posted since it was dead-lettered", p.Story.Link)
+				continue
+			}
+		}
+		queued[p.Story.Link] = true
+		kept = append(kept, p)
+	}
+	return kept
+}
+
+// readDeadLetters reads a dead letter file for --requeue. Requeued posts
+// start over with a full set of attempts
+func readDeadLetters(path string) ([]FailedPost, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var posts []FailedPost
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var p FailedPost
+		if err := json.Unmarshal(scanner.Bytes(), &p); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		p.Attempts = 0
+		posts = append(posts, p)
+	}
+	return posts, scanner.Err()
+}
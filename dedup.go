@@ -0,0 +1,88 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultTitleDedupThreshold is the title similarity above which two
+// stories are treated as the same news
+const defaultTitleDedupThreshold = 0.85
+
+// levenshteinSimilarity scores how alike two strings are, from 0 (nothing
+// in common) to 1 (identical), as one minus their edit distance over the
+// longer length
+func levenshteinSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+
+	// Two rows of the edit distance table are enough
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+// titleDedupThreshold reads TITLE_DEDUP_THRESHOLD, falling back to the
+// default when unset or not a number between 0 and 1
+func titleDedupThreshold() float64 {
+	value := os.Getenv("TITLE_DEDUP_THRESHOLD")
+	if value == "" {
+		return defaultTitleDedupThreshold
+	}
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil || threshold <= 0 || threshold > 1 {
+		log.Printf("Invalid TITLE_DEDUP_THRESHOLD %q, using %.2f", value, defaultTitleDedupThreshold)
+		return defaultTitleDedupThreshold
+	}
+	return threshold
+}
+
+// dedupeTitles drops stories whose title is more similar than threshold to
+// an earlier one's, keeping whichever of the two ranks higher by boosts (the
+// earlier, higher ranked one on a tie) in the earlier one's place
+func dedupeTitles(stories []Story, threshold float64, boosts domainBoosts) []Story {
+	values := boosts.rankValues(stories)
+	var kept []Story
+	var keptValues []float64
+	for j, story := range stories {
+		duplicate := false
+		for i := range kept {
+			similarity := levenshteinSimilarity(strings.ToLower(story.Title), strings.ToLower(kept[i].Title))
+			if similarity <= threshold {
+				continue
+			}
+			duplicate = true
+			if outranks(story, values[j], kept[i], keptValues[i]) {
+				log.Printf("Skipping duplicate title: %s (kept higher ranked %s)", kept[i].Title, story.Title)
+				kept[i], keptValues[i] = story, values[j]
+			} else {
+				log.Printf("Skipping duplicate title: %s (same news as %s)", story.Title, kept[i].Title)
+			}
+			break
+		}
+		if !duplicate {
+			kept = append(kept, story)
+			keptValues = append(keptValues, values[j])
+		}
+	}
+	return kept
+}
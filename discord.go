@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultDiscordEmbedColor is Reddit orange
+	defaultDiscordEmbedColor = 0xFF4500
+	// Discord's documented embed field limits
+	discordTitleLimit       = 256
+	discordDescriptionLimit = 4096
+	discordEmbedsPerMessage = 10
+)
+
+// DiscordEmbed is a Discord rich embed
+type DiscordEmbed struct {
+	Title       string                 `json:"title,omitempty"`
+	URL         string                 `json:"url,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Color       int                    `json:"color,omitempty"`
+	Footer      *DiscordEmbedFooter    `json:"footer,omitempty"`
+	Thumbnail   *DiscordEmbedThumbnail `json:"thumbnail,omitempty"`
+}
+
+// DiscordEmbedFooter is the small text line at the bottom of an embed
+type DiscordEmbedFooter struct {
+	Text string `json:"text"`
+}
+
+// DiscordEmbedThumbnail is the image shown beside an embed
+type DiscordEmbedThumbnail struct {
+	URL string `json:"url"`
+}
+
+// discordPayload is the webhook request body
+type discordPayload struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []DiscordEmbed `json:"embeds"`
+}
+
+// discordEmbedColor reads DISCORD_EMBED_COLOR as hex ("#FF4500", "0xFF4500")
+// or decimal, falling back to the default brand color
+func discordEmbedColor() int {
+	value := strings.TrimSpace(os.Getenv("DISCORD_EMBED_COLOR"))
+	if value == "" {
+		return defaultDiscordEmbedColor
+	}
+	base := 10
+	if trimmed := strings.TrimPrefix(strings.TrimPrefix(value, "#"), "0x"); trimmed != value {
+		value, base = trimmed, 16
+	}
+	color, err := strconv.ParseInt(value, base, 32)
+	if err != nil || color < 0 || color > 0xFFFFFF {
+		return defaultDiscordEmbedColor
+	}
+	return int(color)
+}
+
+// buildDiscordEmbed renders a story as a Discord embed linking to the article
+func buildDiscordEmbed(story Story, summary string) DiscordEmbed {
+	embed := DiscordEmbed{
+		Title: truncateRunes(displayTitle(story), discordTitleLimit),
+		URL:   articleLink(story),
+		Color: discordEmbedColor(),
+	}
+	if summary != story.Title && summary != story.Link {
+		embed.Description = truncateRunes(summary, discordDescriptionLimit)
+	}
+	if domain := storyDomain(articleLink(story)); domain != "" {
+		embed.Footer = &DiscordEmbedFooter{Text: domain}
+	}
+	return embed
+}
+
+// truncateRunes shortens s to at most limit characters, marking the cut with an ellipsis
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// postToDiscord sends embeds to a Discord webhook
+func postToDiscord(webhookURL string, embeds ...DiscordEmbed) error {
+	return postDiscordMessage(webhookURL, "", embeds...)
+}
+
+// postDiscordMessage sends embeds with an optional line of text above them
+func postDiscordMessage(webhookURL, content string, embeds ...DiscordEmbed) error {
+	data, _ := json.Marshal(discordPayload{Content: content, Embeds: embeds})
+
+	return withRetry(DestinationDiscord, func() error {
+		req, err := http.NewRequest("POST", webhookURL, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if err := signWebhookRequest(req, data); err != nil {
+			return err
+		}
+
+		client := &http.Client{Timeout: 15 * time.Second}
+		resp, err := client.Do(req)
+		if err != nil {
+			return &NotifyError{Destination: "discord", Err: err}
+		}
+		defer resp.Body.Close()
+
+		// Discord answers 204 No Content unless ?wait=true is set
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+			return notifyResponseError("discord", resp)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// runMainEnv makes the test binary run the bot itself, so the end-to-end
+// test drives the real main() in a child process with its own environment
+const runMainEnv = "NEWSBOT_E2E_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// webhookRecorder keeps the JSON payloads posted to it, in arrival order
+type webhookRecorder struct {
+	mu       sync.Mutex
+	requests []recordedRequest
+}
+
+type recordedRequest struct {
+	Path    string          `json:"path"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func (rec *webhookRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rec.mu.Lock()
+	rec.requests = append(rec.requests, recordedRequest{Path: r.URL.Path, Payload: body})
+	rec.mu.Unlock()
+	if strings.HasPrefix(r.URL.Path, "/discord") {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	io.WriteString(w, "ok")
+}
+
+// TestEndToEnd runs one day's run of the bot against stand-ins for Reddit,
+// Hugging Face and the Slack and Discord webhooks, and compares everything
+// it posted and archived with the golden files in testdata/e2e. Dates and
+// timings that depend on when the test runs are normalized first
+func TestEndToEnd(t *testing.T) {
+	listing, err := os.ReadFile(filepath.Join("testdata", "e2e", "news.rss"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reddit := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/r/news/top/.rss" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/atom+xml")
+		w.Write(listing)
+	}))
+	defer reddit.Close()
+
+	// Canned summaries, keyed by summarizer input (the story title)
+	var canned map[string]string
+	data, err := os.ReadFile(filepath.Join("testdata", "e2e", "summaries.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &canned); err != nil {
+		t.Fatal(err)
+	}
+	huggingFace := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Inputs string `json:"inputs"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		summary, ok := canned[req.Inputs]
+		if r.URL.Path != hfModelPath || !ok {
+			t.Errorf("unexpected summarization request %s %q", r.URL.Path, req.Inputs)
+			http.Error(w, "unknown input", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]string{{"summary_text": summary}})
+	}))
+	defer huggingFace.Close()
+
+	recorder := &webhookRecorder{}
+	webhooks := httptest.NewServer(recorder)
+	defer webhooks.Close()
+
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive")
+	cmd := exec.Command(os.Args[0])
+	cmd.Dir = dir
+	cmd.Env = []string{
+		runMainEnv + "=1",
+		"REDDIT_BASE_URL=" + reddit.URL,
+		"HF_BASE_URLS=" + huggingFace.URL,
+		"HUGGINGFACE_API_KEY=test",
+		"SLACK_WEBHOOK_URL=" + webhooks.URL + "/slack",
+		"DISCORD_WEBHOOK_URL=" + webhooks.URL + "/discord",
+		"RUN_SUMMARY=true",
+		"SUMMARY_WEBHOOK_URL=" + webhooks.URL + "/summary",
+		"SLACK_MIN_INTER_POST_DELAY_MS=0",
+		"SUMMARY_INPUT_TEMPLATE={{.Title}}",
+		"SKIP_IMAGE_POSTS=true",
+		"PRIORITY_DOMAINS=apnews.com=2",
+		"TIMEZONE=UTC",
+		"STATE_FILE=" + filepath.Join(dir, "state.json"),
+		"HTML_OUTPUT_DIR=" + archive,
+	}
+	started := time.Now().UTC()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("bot run failed: %v\n%s", err, out)
+	}
+
+	// The run's date appears in the Slack header and the archive
+	dates := strings.NewReplacer(
+		localizeDate(started, "en"), "<date>",
+		started.Format("2006-01-02"), "<date>",
+	)
+	timings := regexp.MustCompile(`in [0-9.]+[µm]?s \| HF avg latency: [0-9.]+[µm]?s`)
+
+	var posted bytes.Buffer
+	for _, req := range recorder.requests {
+		var payload any
+		if err := json.Unmarshal(req.Payload, &payload); err != nil {
+			t.Fatalf("%s payload isn't JSON: %v\n%s", req.Path, err, req.Payload)
+		}
+		enc := json.NewEncoder(&posted)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(recordedRequest{Path: req.Path, Payload: mustMarshal(t, payload)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := timings.ReplaceAllString(dates.Replace(posted.String()), "in <elapsed> | HF avg latency: <latency>")
+	checkGolden(t, filepath.Join("e2e", "webhooks.golden"), []byte(got))
+
+	entries, err := os.ReadDir(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		content, err := os.ReadFile(filepath.Join(archive, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden := "archive-" + strings.ReplaceAll(dates.Replace(name), "<date>", "day") + ".golden"
+		checkGolden(t, filepath.Join("e2e", golden), []byte(dates.Replace(string(content))))
+	}
+}
+
+// mustMarshal re-encodes a decoded payload with sorted keys and without
+// HTML escaping, so the golden file reads like what was sent
+func mustMarshal(t *testing.T, v any) json.RawMessage {
+	t.Helper()
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.TrimSpace(b.Bytes())
+}
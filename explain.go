@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+)
+
+// runExplainCommand implements `explain <url>`: it runs one post or article
+// URL through the selection pipeline with the current config and prints
+// each step's verdict. It never writes state
+func runExplainCommand(args []string) {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	snapshot := fs.String("offline", "", "evaluate against this feed snapshot instead of fetching the live listing")
+	since := fs.String("since", "", "same as the main --since flag")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal("usage: explain [--offline <snapshot>] [--since <value>] <reddit-post-url-or-article-url>")
+	}
+	target := fs.Arg(0)
+
+	state, err := loadState(stateFilePath())
+	if err != nil {
+		log.Fatalf("Error loading state file: %v", err)
+	}
+	cutoff, err := resolveSince(*since, state.LastRunAt)
+	if err != nil {
+		log.Fatalf("Invalid --since value: %v", err)
+	}
+
+	var data []byte
+	var format listingFormat
+	if *snapshot != "" {
+		data, format, err = readSnapshot(*snapshot)
+	} else {
+		data, format, err = fetchCandidateListing()
+	}
+	if err != nil {
+		log.Fatalf("Failed to fetch stories: %v", err)
+	}
+	candidates, err := parseListing(data, format)
+	if err != nil {
+		log.Fatalf("Failed to parse stories: %v", err)
+	}
+
+	included := true
+	verdict := func(ok bool, format string, args ...interface{}) {
+		mark := "✓"
+		if !ok {
+			mark = "✗"
+			included = false
+		}
+		fmt.Printf("%s %s\n", mark, fmt.Sprintf(format, args...))
+	}
+
+	// 1. Is it in the listing at all?
+	position := -1
+	for i, candidate := range candidates {
+		if sameURL(candidate.Link, target) || sameURL(articleLink(candidate), target) {
+			position = i
+			break
+		}
+	}
+	var story Story
+	if position >= 0 {
+		story = candidates[position]
+		verdict(true, "in listing at position %d of %d", position+1, len(candidates))
+	} else {
+		if *snapshot == "" && strings.Contains(target, "reddit.com/") {
+			if post, err := fetchRedditPost(target); err == nil {
+				story = post
+			}
+		}
+		verdict(false, "not in the current listing")
+		if story.Title == "" {
+			fmt.Println("\nFinal decision: EXCLUDED")
+			return
+		}
+	}
+	fmt.Printf("  title: %s\n  author: %s\n", story.Title, story.Author)
+	if note := priorityNote(story, priorityDomainsFromEnv()); note != "" {
+		fmt.Printf("  %s\n", note)
+	}
+
+	// 2. The run's filters (store checks are read-only), applied to the
+	// whole listing since some of them compare stories with each other
+	store, err := openStore()
+	if err != nil {
+		log.Fatalf("Failed to open story store: %v", err)
+	}
+	if store == nil {
+		fmt.Println("  no story store configured, repost and author checks skipped")
+	}
+	pool := candidates
+	if position < 0 {
+		pool = []Story{story}
+	}
+	stages := selectionStages(cutoff, store)
+	survivors := selectStories(pool, stages, func(stage selectionStage, input, kept []Story) {
+		// The input's copy carries the tags selection adds, like ImagePost
+		for _, current := range input {
+			if current.Link != story.Link {
+				continue
+			}
+			if containsLink(kept, story.Link) {
+				verdict(true, "passes the %s filter: %s", stage.name, stage.explain(current, input))
+			} else {
+				verdict(false, "dropped by the %s filter: %s", stage.name, stage.explain(current, input))
+			}
+		}
+	})
+
+	// 3. Rank among the stories that survive the same filters
+	if included && position >= 0 {
+		limit := runSummaryLimit(survivors)
+		for rank, survivor := range survivors {
+			if survivor.Link == story.Link {
+				op := "≤"
+				if rank >= limit {
+					op = ">"
+				}
+				verdict(rank < limit, "rank %d after filters %s summary limit %d", rank+1, op, limit)
+				break
+			}
+		}
+	}
+
+	// 4. How it would be summarized
+	domain := storyDomain(articleLink(story))
+	if permitted, reason, err := checkSummarizationPermission(domain); err != nil {
+		fmt.Printf("  PROHIBITED_DOMAINS_FILE unreadable: %v\n", err)
+	} else if !permitted {
+		fmt.Printf("  domain %s ∈ PROHIBITED_DOMAINS_FILE (%s): summary would be replaced by %q\n", domain, reason, notPermittedNote)
+	} else {
+		fmt.Printf("  domain %s ∉ PROHIBITED_DOMAINS_FILE: may be summarized\n", domain)
+	}
+	if threshold, ok := titlePassthroughEase(); ok {
+		ease := computeReadingLevel(story.Title)
+		if ease > threshold {
+			fmt.Printf("  reading ease %.1f > %.1f: title would be posted without a summary\n", ease, threshold)
+		} else {
+			fmt.Printf("  reading ease %.1f ≤ %.1f: title would be summarized\n", ease, threshold)
+		}
+	}
+
+	if included {
+		fmt.Println("\nFinal decision: INCLUDED")
+	} else {
+		fmt.Println("\nFinal decision: EXCLUDED")
+	}
+}
+
+// sameURL compares URLs ignoring a trailing slash
+func sameURL(a, b string) bool {
+	return strings.TrimRight(a, "/") == strings.TrimRight(b, "/")
+}
+
+// fetchRedditPost loads a single Reddit post's metadata from its permalink
+func fetchRedditPost(permalink string) (Story, error) {
+	// Share links carry a query string, so .json goes on the path
+	u, err := url.Parse(permalink)
+	if err != nil {
+		return Story{}, err
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + ".json"
+	u.Fragment = ""
+	data, err := fetchRaw(u.String(), "application/json")
+	if err != nil {
+		return Story{}, err
+	}
+	// A post's JSON is the post listing followed by the comments listing
+	var listings []json.RawMessage
+	if err := json.Unmarshal(data, &listings); err != nil || len(listings) == 0 {
+		return Story{}, fmt.Errorf("unexpected post JSON from %s", permalink)
+	}
+	stories, err := parseRedditListing(listings[0])
+	if err != nil || len(stories) == 0 {
+		return Story{}, fmt.Errorf("no post found at %s", permalink)
+	}
+	return stories[0], nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// extractiveLabel marks summaries picked from the input instead of generated
+const extractiveLabel = "[Key sentences:]"
+
+var (
+	// sentencePattern matches a sentence and its closing punctuation
+	sentencePattern = regexp.MustCompile(`[^.!?]+[.!?]*`)
+	// urlPattern matches links, which aren't sentences worth extracting
+	urlPattern = regexp.MustCompile(`https?://\S+`)
+)
+
+// ExtractiveSummarizer summarizes text locally by picking its highest
+// scoring sentences by TF-IDF, treating each sentence as a document
+type ExtractiveSummarizer struct {
+	Sentences int // how many sentences to keep
+}
+
+// Summarize returns the top sentences of text in their original order
+func (e ExtractiveSummarizer) Summarize(text string) string {
+	text = urlPattern.ReplaceAllString(text, " ")
+	var sentences []string
+	for _, s := range sentencePattern.FindAllString(text, -1) {
+		s = strings.Trim(strings.TrimSpace(s), "-–— ")
+		if s != "" {
+			sentences = append(sentences, s)
+		}
+	}
+	if len(sentences) <= e.Sentences {
+		return strings.Join(sentences, " ")
+	}
+
+	// Words in every sentence score zero; rarer words count for more
+	counts := make([]map[string]int, len(sentences))
+	totals := make([]int, len(sentences))
+	docFreq := make(map[string]int)
+	for i, s := range sentences {
+		counts[i] = make(map[string]int)
+		for _, word := range strings.FieldsFunc(strings.ToLower(s), isWordBreak) {
+			totals[i]++
+			if len(word) > 3 && !stopWords[word] {
+				counts[i][word]++
+			}
+		}
+		for word := range counts[i] {
+			docFreq[word]++
+		}
+	}
+	scores := make([]float64, len(sentences))
+	for i := range sentences {
+		for word, n := range counts[i] {
+			tf := float64(n) / float64(totals[i])
+			scores[i] += tf * math.Log(float64(len(sentences))/float64(docFreq[word]))
+		}
+	}
+
+	order := make([]int, len(sentences))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	top := order[:e.Sentences]
+	sort.Ints(top)
+
+	picked := make([]string, len(top))
+	for i, index := range top {
+		picked[i] = sentences[index]
+	}
+	return strings.Join(picked, " ")
+}
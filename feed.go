@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// listingFormat names how a fetched listing body is parsed
+type listingFormat string
+
+const (
+	formatRSS      listingFormat = "rss"
+	formatJSONFeed listingFormat = "jsonfeed"
+	formatReddit   listingFormat = "reddit"
+)
+
+// Accept headers for the listing formats
+const (
+	rssAccept      = "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8"
+	jsonFeedAccept = "application/feed+json, application/json"
+)
+
+// parseListing converts a raw listing body into stories
+func parseListing(data []byte, format listingFormat) ([]Story, error) {
+	switch format {
+	case formatJSONFeed:
+		return parseJSONFeed(data)
+	case formatReddit:
+		return parseRedditListing(data)
+	default:
+		return parseRSSFeed(data)
+	}
+}
+
+// fetchRaw downloads url and returns the response body
+func fetchRaw(url, accept string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", fetchUserAgent)
+	req.Header.Set("Accept", accept)
+	// Asking explicitly turns off the transport's transparent gzip handling,
+	// so the compressed size can be measured
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, &statusError{Service: "Feed", StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return decodeBody(url, resp.Header.Get("Content-Encoding"), raw)
+}
+
+// decodeBody decompresses a gzip or deflate response body, logging how many
+// bytes compression saved
+func decodeBody(source, encoding string, raw []byte) ([]byte, error) {
+	var r io.ReadCloser
+	var err error
+	switch strings.ToLower(encoding) {
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(raw))
+	case "deflate":
+		// Deflate is meant to be zlib-wrapped, but some servers send a raw
+		// deflate stream instead
+		r, err = zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			r, err = flate.NewReader(bytes.NewReader(raw)), nil
+		}
+	default:
+		return raw, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s body: %w", encoding, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s body: %w", encoding, err)
+	}
+	slog.Info("feed_fetched", "url", source, "encoding", encoding,
+		"compressed_bytes", len(raw), "uncompressed_bytes", len(data),
+		"rss_bytes_saved", len(data)-len(raw))
+	return data, nil
+}
+
+// feedURL returns the feed to pull stories from, defaulting to r/news
+func feedURL() string {
+	if feed := os.Getenv("FEED_URL"); feed != "" {
+		return feed
+	}
+	return redditBaseURL() + redditRSSPath
+}
+
+// isJSONFeed reports whether url should be parsed as a JSON Feed. FEED_FORMAT
+// forces the choice; otherwise a .json path is taken to mean JSON Feed
+func isJSONFeed(feedURL string) bool {
+	switch os.Getenv("FEED_FORMAT") {
+	case "jsonfeed":
+		return true
+	case "rss":
+		return false
+	}
+	path := strings.SplitN(feedURL, "?", 2)[0]
+	return strings.HasSuffix(path, ".json")
+}
+
+// jsonFeed is the subset of the JSON Feed (jsonfeed.org) format the bot uses
+type jsonFeed struct {
+	Items []struct {
+		Title         string `json:"title"`
+		URL           string `json:"url"`
+		ExternalURL   string `json:"external_url"`
+		DatePublished string `json:"date_published"`
+		Authors       []struct {
+			Name string `json:"name"`
+		} `json:"authors"`
+		// Author is the JSON Feed 1.0 field replaced by authors in 1.1
+		Author *struct {
+			Name string `json:"name"`
+		} `json:"author"`
+	} `json:"items"`
+}
+
+// fetchJSONFeed downloads and parses a JSON Feed into stories
+func fetchJSONFeed(feedURL string) ([]Story, error) {
+	data, err := fetchRaw(feedURL, jsonFeedAccept)
+	if err != nil {
+		return nil, err
+	}
+	return parseJSONFeed(data)
+}
+
+// parseJSONFeed parses a JSON Feed document into stories
+func parseJSONFeed(data []byte) ([]Story, error) {
+	var feed jsonFeed
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return nil, err
+	}
+
+	var stories []Story
+	for _, item := range feed.Items {
+		story := Story{
+			Title: item.Title,
+			Link:  item.URL,
+		}
+		if story.Link == "" {
+			story.Link = item.ExternalURL
+		} else if item.ExternalURL != story.Link {
+			story.ArticleURL = item.ExternalURL
+		}
+		if len(item.Authors) > 0 {
+			story.Author = item.Authors[0].Name
+		} else if item.Author != nil {
+			story.Author = item.Author.Name
+		}
+		if published, err := time.Parse(time.RFC3339, item.DatePublished); err == nil {
+			story.Published = published
+		}
+		stories = append(stories, story)
+	}
+	return stories, nil
+}
+
+// feedLinkTypes are the <link rel="alternate"> types that point at a feed
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":  true,
+	"application/atom+xml": true,
+}
+
+// discoverFeedFromURL fetches an HTML page and returns the first RSS or Atom
+// feed it advertises, resolved against the page URL
+func discoverFeedFromURL(pageURL string) (string, error) {
+	page, err := fetchRaw(pageURL, "text/html")
+	if err != nil {
+		return "", err
+	}
+	return discoverFeedFromHTML(page, pageURL)
+}
+
+// discoverFeedFromHTML returns the first RSS or Atom feed advertised by an
+// already downloaded HTML page, resolved against the page URL
+func discoverFeedFromHTML(page []byte, pageURL string) (string, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+	if err != nil {
+		return "", err
+	}
+
+	var feed string
+	doc.Find("link[href]").EachWithBreak(func(_ int, link *goquery.Selection) bool {
+		rel := strings.Fields(strings.ToLower(link.AttrOr("rel", "")))
+		linkType := strings.ToLower(strings.TrimSpace(link.AttrOr("type", "")))
+		if !containsString(rel, "alternate") || !feedLinkTypes[linkType] {
+			return true
+		}
+		href, err := url.Parse(strings.TrimSpace(link.AttrOr("href", "")))
+		if err != nil {
+			return true
+		}
+		feed = base.ResolveReference(href).String()
+		return false
+	})
+	if feed == "" {
+		return "", fmt.Errorf("no RSS or Atom feed advertised at %s", pageURL)
+	}
+	return feed, nil
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestDiscoverFeedFromHTML(t *testing.T) {
+	page := []byte(`<html><head>
+<link rel="stylesheet" href="/style.css">
+<link rel="alternate" type="application/atom+xml" href="../feeds/atom.xml">
+<link rel="alternate" type="application/rss+xml" href="https://example.com/rss">
+</head></html>`)
+	got, err := discoverFeedFromHTML(page, "https://example.com/news/today")
+	if err != nil || got != "https://example.com/feeds/atom.xml" {
+		t.Errorf("discoverFeedFromHTML() = %q, %v; want the first feed, resolved", got, err)
+	}
+	if _, err := discoverFeedFromHTML([]byte("<html></html>"), "https://example.com/"); err == nil {
+		t.Error("discoverFeedFromHTML() found a feed on a page without one")
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:embed templates/*.html
+var builtinTemplates embed.FS
+
+// htmlIndexDays is how many recent digests the index page links to
+const htmlIndexDays = 30
+
+// digestEntry is one story as shown on the HTML digest page
+type digestEntry struct {
+	Title     string
+	Headline  string // the story's own title, without badges
+	Link      string
+	Summary   string
+	Domain    string
+	Score     int
+	Author    string
+	Published time.Time
+	JSONLD    json.RawMessage `json:"-"` // schema.org NewsArticle, filled in when the page is written
+}
+
+// newDigestEntry builds the HTML page entry for a posted story
+func newDigestEntry(story Story, summary string) digestEntry {
+	entry := digestEntry{
+		Title:     displayTitle(story),
+		Headline:  story.Title,
+		Link:      story.Link,
+		Domain:    storyDomain(articleLink(story)),
+		Score:     story.Score,
+		Author:    story.Author,
+		Published: story.Published,
+	}
+	if !story.SkipSummary && summary != story.Title {
+		entry.Summary = summary
+	}
+	return entry
+}
+
+// storyDomain returns the host of link without a leading "www."
+func storyDomain(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// loadHTMLTemplate returns the named page template, preferring an override
+// in HTML_TEMPLATE_DIR when one exists
+func loadHTMLTemplate(name string) (*template.Template, error) {
+	if dir := os.Getenv("HTML_TEMPLATE_DIR"); dir != "" {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return template.ParseFiles(path)
+		}
+	}
+	return template.ParseFS(builtinTemplates, "templates/"+name)
+}
+
+// writeHTMLDigest adds entries to the digest page for day (in TIMEZONE) and
+// refreshes the index page in dir. The day's entries so far are kept in a
+// <date>.json file next to the page, so each run adds to the page rather
+// than replacing it
+func writeHTMLDigest(dir string, day time.Time, entries []digestEntry) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	loc, err := botLocation()
+	if err != nil {
+		return fmt.Errorf("TIMEZONE: %w", err)
+	}
+	date := day.In(loc).Format("2006-01-02")
+	entries, err = mergeDigestEntries(filepath.Join(dir, date+".json"), entries)
+	if err != nil {
+		return err
+	}
+
+	// Structured data for search engines, embedded in the page and also
+	// written alongside it
+	articles := make([]json.RawMessage, 0, len(entries))
+	for i := range entries {
+		data, err := toJSONLD(entries[i])
+		if err != nil {
+			return err
+		}
+		entries[i].JSONLD = data
+		articles = append(articles, data)
+	}
+	data, err := json.MarshalIndent(articles, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, date+".jsonld"), data, 0o644); err != nil {
+		return err
+	}
+
+	page, err := loadHTMLTemplate("digest.html")
+	if err != nil {
+		return err
+	}
+	err = renderTemplateFile(page, filepath.Join(dir, date+".html"), map[string]interface{}{
+		"Date":    date,
+		"Stories": entries,
+	})
+	if err != nil {
+		return err
+	}
+
+	days, err := digestDays(dir)
+	if err != nil {
+		return err
+	}
+	index, err := loadHTMLTemplate("index.html")
+	if err != nil {
+		return err
+	}
+	return renderTemplateFile(index, filepath.Join(dir, "index.html"), map[string]interface{}{
+		"Days": days,
+	})
+}
+
+// mergeDigestEntries appends entries to the ones saved at path, skipping
+// stories already on the page, and saves the result back
+func mergeDigestEntries(path string, entries []digestEntry) ([]digestEntry, error) {
+	var merged []digestEntry
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(data, &merged); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	onPage := make(map[string]bool)
+	for _, entry := range merged {
+		onPage[entry.Link] = true
+	}
+	for _, entry := range entries {
+		if !onPage[entry.Link] {
+			onPage[entry.Link] = true
+			merged = append(merged, entry)
+		}
+	}
+
+	data, err = json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// digestDays lists the dates of digest pages in dir, newest first
+func digestDays(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "????-??-??.html"))
+	if err != nil {
+		return nil, err
+	}
+	var days []string
+	for _, match := range matches {
+		day := strings.TrimSuffix(filepath.Base(match), ".html")
+		if _, err := time.Parse("2006-01-02", day); err == nil {
+			days = append(days, day)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(days)))
+	if len(days) > htmlIndexDays {
+		days = days[:htmlIndexDays]
+	}
+	return days, nil
+}
+
+// renderTemplateFile executes tmpl into path via a temporary file
+func renderTemplateFile(tmpl *template.Template, path string, data interface{}) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Execute(f, data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
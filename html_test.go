@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got with testdata/name, rewriting it under -update
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s differs from the golden file (rerun with -update to accept):\n%s", name, got)
+	}
+}
+
+func TestWriteHTMLDigest(t *testing.T) {
+	t.Setenv("TIMEZONE", "America/New_York")
+	t.Setenv("HTML_TEMPLATE_DIR", "")
+	dir := t.TempDir()
+
+	// 01:00 UTC is still the previous evening in New York
+	firstRun := time.Date(2026, 10, 14, 1, 0, 0, 0, time.UTC)
+	council := Story{
+		Title:      "City council votes to expand bike lanes",
+		Link:       "https://www.reddit.com/r/news/comments/abc123/city_council_votes/",
+		ArticleURL: "https://www.example.com/2026/10/13/bike-lanes.html",
+		Author:     "/u/alice",
+		Score:      1520,
+		Published:  time.Date(2026, 10, 13, 20, 30, 0, 0, time.UTC),
+	}
+	escaping := Story{
+		Title:     `Site says <script>alert("hi")</script> & 'quotes' are fine`,
+		Link:      "https://example.org/a?b=1&c=<2>",
+		Author:    "/u/bob",
+		Score:     87,
+		Published: time.Date(2026, 10, 13, 22, 0, 0, 0, time.UTC),
+		Trending:  true,
+	}
+	passthrough := Story{
+		Title: "Storm closes schools",
+		Link:  "https://news.example.net/storm",
+	}
+
+	runs := []struct {
+		at      time.Time
+		entries []digestEntry
+	}{
+		{firstRun, []digestEntry{newDigestEntry(council, "The council approved 40 miles of new lanes.")}},
+		// A later run adds to the page, and doesn't repeat a story already on it
+		{firstRun.Add(time.Hour), []digestEntry{
+			newDigestEntry(escaping, `It ends the script with </script><!-- and "more".`),
+			newDigestEntry(council, "The council approved 40 miles of new lanes."),
+			newDigestEntry(passthrough, passthrough.Title),
+		}},
+		// A run that posts nothing leaves the page as it was
+		{firstRun.Add(2 * time.Hour), nil},
+	}
+	for _, run := range runs {
+		if err := writeHTMLDigest(dir, run.at, run.entries); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for file, golden := range map[string]string{
+		"2026-10-13.html":   "digest.html.golden",
+		"2026-10-13.jsonld": "digest.jsonld.golden",
+		"index.html":        "index.html.golden",
+	} {
+		got, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, golden, got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2026-10-14.html")); !os.IsNotExist(err) {
+		t.Errorf("digest written under the UTC date instead of TIMEZONE's")
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hfEndpoints holds the Hugging Face base URLs for the current run
+var hfEndpoints = newEndpointPool([]string{hfBaseURL})
+
+// endpointPool tracks the health of interchangeable base URLs serving the
+// same model. Once an endpoint succeeds it is preferred for the rest of the run
+type endpointPool struct {
+	mu        sync.Mutex
+	urls      []string
+	unhealthy map[string]bool
+	chosen    string
+}
+
+// newEndpointPool creates a pool that tries urls in order
+func newEndpointPool(urls []string) *endpointPool {
+	return &endpointPool{urls: urls, unhealthy: make(map[string]bool)}
+}
+
+// Candidates returns the endpoints to try, sticky choice first, then the
+// remaining healthy ones in configured order
+func (p *endpointPool) Candidates() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var candidates []string
+	if p.chosen != "" {
+		candidates = append(candidates, p.chosen)
+	}
+	for _, url := range p.urls {
+		if url != p.chosen && !p.unhealthy[url] {
+			candidates = append(candidates, url)
+		}
+	}
+	if len(candidates) == 0 {
+		// Everything has failed; give every endpoint another chance
+		return append(candidates, p.urls...)
+	}
+	return candidates
+}
+
+// MarkSuccess makes url the sticky choice for the rest of the run
+func (p *endpointPool) MarkSuccess(url string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.chosen = url
+	delete(p.unhealthy, url)
+}
+
+// MarkFailure records url as unhealthy
+func (p *endpointPool) MarkFailure(url string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unhealthy[url] = true
+	if p.chosen == url {
+		p.chosen = ""
+	}
+}
+
+// Chosen returns the endpoint that last succeeded, if any
+func (p *endpointPool) Chosen() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.chosen
+}
+
+// hfBaseURLs reads the comma-separated HF_BASE_URLS list, defaulting to the
+// public inference API
+func hfBaseURLs() []string {
+	var urls []string
+	for _, url := range strings.Split(os.Getenv("HF_BASE_URLS"), ",") {
+		if url = strings.TrimRight(strings.TrimSpace(url), "/"); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	if len(urls) == 0 {
+		return []string{hfBaseURL}
+	}
+	return urls
+}
+
+// endpointError is returned when an endpoint itself is failing, as opposed
+// to the request being rejected
+type endpointError struct {
+	err error
+}
+
+func (e *endpointError) Error() string { return e.err.Error() }
+
+func (e *endpointError) Unwrap() error { return e.err }
+
+// summarizeWithHuggingFace uses the Hugging Face inference API to summarize
+// text, retrying per the Hugging Face retry policy
+func summarizeWithHuggingFace(apiKey, text string, length SummaryLength) (string, error) {
+	summaries, err := summarizeCandidates(apiKey, text, length, 1)
+	if err != nil {
+		return "", err
+	}
+	return summaries[0], nil
+}
+
+// summarizeCandidates asks the model for up to count alternative summaries
+// of text with beam search. The API may return fewer
+func summarizeCandidates(apiKey, text string, length SummaryLength, count int) ([]string, error) {
+	var summaries []string
+	err := withRetry(DestinationHuggingFace, func() error {
+		var err error
+		summaries, err = summarizeWithFailover(apiKey, text, length, count)
+		return err
+	})
+	return summaries, err
+}
+
+// summarizeWithFailover tries each configured base URL in turn until one
+// responds
+func summarizeWithFailover(apiKey, text string, length SummaryLength, count int) ([]string, error) {
+	var lastErr error
+	for _, baseURL := range hfEndpoints.Candidates() {
+		summaries, err := summarizeAtEndpoint(baseURL, apiKey, text, length, count)
+		if err == nil {
+			hfEndpoints.MarkSuccess(baseURL)
+			return summaries, nil
+		}
+		if _, ok := err.(*endpointError); !ok {
+			return nil, err
+		}
+		hfEndpoints.MarkFailure(baseURL)
+		lastErr = fmt.Errorf("%s: %w", baseURL, err)
+	}
+	return nil, lastErr
+}
+
+// summarizeAtEndpoint sends a single summarization request to baseURL and
+// returns the summaries in the response
+func summarizeAtEndpoint(baseURL, apiKey, text string, length SummaryLength, count int) ([]string, error) {
+	payload := map[string]interface{}{"inputs": text}
+	parameters := map[string]int{}
+	if length.MaxLength > 0 {
+		parameters["min_length"] = length.MinLength
+		parameters["max_length"] = length.MaxLength
+	}
+	if count > 1 {
+		// Each returned sequence needs its own beam
+		parameters["num_return_sequences"] = count
+		parameters["num_beams"] = max(count, 4)
+	}
+	if len(parameters) > 0 {
+		payload["parameters"] = parameters
+	}
+	body, _ := json.Marshal(payload)
+
+	req, err := http.NewRequest("POST", baseURL+hfModelPath, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 40 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, &endpointError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		err := &statusError{Service: "Hugging Face", StatusCode: resp.StatusCode, Status: resp.Status}
+		if resp.StatusCode >= 500 {
+			return nil, &endpointError{err}
+		}
+		return nil, err
+	}
+
+	var result []map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	var summaries []string
+	for _, r := range result {
+		if r["summary_text"] != "" {
+			summaries = append(summaries, r["summary_text"])
+		}
+	}
+	if len(summaries) == 0 {
+		return []string{"Summary unavailable"}, nil
+	}
+	return summaries, nil
+}
+
+const (
+	// bartMaxTokens is bart-large-cnn's input limit
+	bartMaxTokens = 1024
+	// chunkOverlapTokens is how much context consecutive chunks share
+	chunkOverlapTokens = 64
+	// wordsPerToken approximates English words per model token
+	wordsPerToken = 0.75
+)
+
+// chunkText splits text into pieces of at most maxTokens, overlapping by
+// overlapTokens, using word count as an approximation of token count
+func chunkText(text string, maxTokens int, overlapTokens int) []string {
+	words := strings.Fields(text)
+	size := int(float64(maxTokens) * wordsPerToken)
+	overlap := int(float64(overlapTokens) * wordsPerToken)
+	if size <= 0 {
+		size = 1
+	}
+	if overlap >= size {
+		overlap = size - 1
+	}
+	if len(words) <= size {
+		return []string{strings.Join(words, " ")}
+	}
+
+	var chunks []string
+	for start := 0; start < len(words); start += size - overlap {
+		end := start + size
+		if end > len(words) {
+			end = len(words)
+		}
+		chunks = append(chunks, strings.Join(words[start:end], " "))
+		if end == len(words) {
+			break
+		}
+	}
+	return chunks
+}
+
+// summarizeChunked summarizes each chunk, then summarizes the combined chunk
+// summaries at the target length, repeating until the result fits in a
+// single model input
+func summarizeChunked(apiKey string, chunks []string, length SummaryLength) (string, error) {
+	var partials []string
+	for _, chunk := range chunks {
+		summary, err := summarizeWithHuggingFace(apiKey, chunk, SummaryLength{})
+		if err != nil {
+			return "", err
+		}
+		partials = append(partials, summary)
+	}
+	if len(partials) == 1 {
+		return partials[0], nil
+	}
+
+	combined := chunkText(strings.Join(partials, " "), bartMaxTokens, chunkOverlapTokens)
+	if len(combined) == 1 {
+		return summarizeWithHuggingFace(apiKey, combined[0], length)
+	}
+	return summarizeChunked(apiKey, combined, length)
+}
+
+// summarizeText summarizes text, splitting it into chunks first when it is
+// longer than the model accepts
+func summarizeText(apiKey, text string, length SummaryLength) (string, error) {
+	chunks := chunkText(text, bartMaxTokens, chunkOverlapTokens)
+	if len(chunks) > 1 {
+		return summarizeChunked(apiKey, chunks, length)
+	}
+	return summarizeWithHuggingFace(apiKey, text, length)
+}
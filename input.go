@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// legacySummaryInput is the original "title - link" summarizer input. The
+// link's slug and subreddit can leak into summaries, so it is deprecated
+const legacySummaryInput = "{{.Title}} - {{.Link}}"
+
+// maxLoggedInputRunes caps how much of a summarizer input is logged or traced
+const maxLoggedInputRunes = 200
+
+// summaryInput builds the summarizer input from a story
+var summaryInput = template.Must(template.New("input").Parse(legacySummaryInput))
+
+// loadSummaryInputTemplate reads SUMMARY_INPUT_TEMPLATE, a text/template
+// over Story (e.g. "{{.Title}}"), reporting whether the legacy default is
+// in use
+func loadSummaryInputTemplate() (*template.Template, bool, error) {
+	value := os.Getenv("SUMMARY_INPUT_TEMPLATE")
+	if value == "" {
+		return summaryInput, true, nil
+	}
+	tmpl, err := template.New("input").Parse(value)
+	if err != nil {
+		return nil, false, err
+	}
+	// Catch references to fields Story doesn't have before the first story
+	// does. The sample has every optional field set, so a template reading
+	// them still validates
+	if err := tmpl.Execute(&strings.Builder{}, sampleInputStory); err != nil {
+		return nil, false, err
+	}
+	if err := tmpl.Execute(&strings.Builder{}, Story{}); err != nil {
+		log.Printf("SUMMARY_INPUT_TEMPLATE fails for stories without optional fields (%v); wrap them in {{with}}, e.g. {{with .CompanyInfo}}{{.Name}}{{end}}", err)
+	}
+	return tmpl, false, nil
+}
+
+// sampleInputStory is the story SUMMARY_INPUT_TEMPLATE is checked against
+var sampleInputStory = Story{
+	Title:       "Sample title",
+	Link:        "https://www.reddit.com/r/news/comments/sample/",
+	Author:      "/u/sample",
+	ArticleURL:  "https://example.com/sample",
+	Score:       1,
+	Published:   time.Unix(0, 0),
+	CompanyInfo: &ClearbitCompany{Name: "Example", Description: "An example company", Logo: "https://example.com/logo.png"},
+}
+
+// buildSummaryInput renders the summarizer input for story and records it,
+// truncated, at debug level and in the story's trace
+func buildSummaryInput(story Story, logs *storyLog) (string, error) {
+	var b strings.Builder
+	if err := summaryInput.Execute(&b, story); err != nil {
+		return "", fmt.Errorf("summary input template: %w", err)
+	}
+	input := strings.TrimSpace(b.String())
+	logged := truncateRunes(input, maxLoggedInputRunes)
+	slog.Debug("summarizer_input", "url", story.Link, "input", logged)
+	logs.setInput(logged)
+	return input, nil
+}
@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestLoadSummaryInputTemplate(t *testing.T) {
+	for _, tc := range []struct {
+		template string
+		wantErr  bool
+	}{
+		{"{{.Title}}", false},
+		{"{{.CompanyInfo.Name}}: {{.Title}}", false},
+		{"{{with .CompanyInfo}}{{.Name}}{{end}}", false},
+		{"{{.Nope}}", true},
+	} {
+		t.Setenv("SUMMARY_INPUT_TEMPLATE", tc.template)
+		if _, _, err := loadSummaryInputTemplate(); (err != nil) != tc.wantErr {
+			t.Errorf("loadSummaryInputTemplate() with %q: err = %v, want error %v", tc.template, err, tc.wantErr)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// jsonLDThing is a named schema.org entity, such as a Person or Organization
+type jsonLDThing struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+// newsArticleJSONLD is a schema.org NewsArticle
+type newsArticleJSONLD struct {
+	Context       string       `json:"@context"`
+	Type          string       `json:"@type"`
+	Headline      string       `json:"headline"`
+	URL           string       `json:"url"`
+	DatePublished string       `json:"datePublished,omitempty"`
+	Author        *jsonLDThing `json:"author,omitempty"`
+	Description   string       `json:"description,omitempty"`
+	Publisher     *jsonLDThing `json:"publisher,omitempty"`
+}
+
+// jsonLDHeadlineLimit is the headline length search engines display
+const jsonLDHeadlineLimit = 110
+
+// toJSONLD describes a digest entry as a schema.org NewsArticle
+func toJSONLD(entry digestEntry) (json.RawMessage, error) {
+	article := newsArticleJSONLD{
+		Context:     "https://schema.org",
+		Type:        "NewsArticle",
+		Headline:    truncateRunes(entry.Headline, jsonLDHeadlineLimit),
+		URL:         entry.Link,
+		Description: entry.Summary,
+	}
+	if !entry.Published.IsZero() {
+		article.DatePublished = entry.Published.UTC().Format(time.RFC3339)
+	}
+	if knownAuthor(entry.Author) {
+		article.Author = &jsonLDThing{Type: "Person", Name: entry.Author}
+	}
+	if entry.Domain != "" {
+		article.Publisher = &jsonLDThing{Type: "Organization", Name: entry.Domain}
+	}
+	return json.Marshal(article)
+}
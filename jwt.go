@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+)
+
+// jwtSubject identifies the bot as the signer of webhook payloads
+const jwtSubject = "reddit-news-bot"
+
+// jwtClaims are the claims of a webhook signing token
+type jwtClaims struct {
+	Subject       string `json:"sub"`
+	IssuedAt      int64  `json:"iat"`
+	ExpiresAt     int64  `json:"exp"`
+	PayloadSHA256 string `json:"payload_sha256"`
+}
+
+// signPayloadWithJWT returns an HS256 JWT, valid for ttl, whose
+// payload_sha256 claim is the hex SHA-256 of payload. Receivers can check
+// both the signature and that the body they got is the one that was signed
+func signPayloadWithJWT(payload []byte, secret string, ttl time.Duration) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	sum := sha256.Sum256(payload)
+	claims, err := json.Marshal(jwtClaims{
+		Subject:       jwtSubject,
+		IssuedAt:      now.Unix(),
+		ExpiresAt:     now.Add(ttl).Unix(),
+		PayloadSHA256: hex.EncodeToString(sum[:]),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + enc.EncodeToString(mac.Sum(nil)), nil
+}
+
+// signWebhookRequest adds an Authorization: Bearer JWT for payload to req
+// when WEBHOOK_JWT_SECRET is set. Tokens last WEBHOOK_JWT_TTL_SECONDS
+// (default 300)
+func signWebhookRequest(req *http.Request, payload []byte) error {
+	secret := os.Getenv("WEBHOOK_JWT_SECRET")
+	if secret == "" {
+		return nil
+	}
+	ttl := time.Duration(envInt("WEBHOOK_JWT_TTL_SECONDS", 300)) * time.Second
+	token, err := signPayloadWithJWT(payload, secret, ttl)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
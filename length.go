@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SummaryLength is a target summary length, passed to the model as
+// min_length/max_length. The zero value leaves the model's defaults
+type SummaryLength struct {
+	Name      string
+	MinLength int
+	MaxLength int
+}
+
+// summaryLengthPresets are the lengths a policy can select, in model tokens
+var summaryLengthPresets = map[string]SummaryLength{
+	"short":  {Name: "short", MinLength: 10, MaxLength: 40},
+	"medium": {Name: "medium", MinLength: 30, MaxLength: 90},
+	"long":   {Name: "long", MinLength: 60, MaxLength: 142},
+}
+
+// lengthRule applies a length preset to stories ranked first..last
+// (last == 0 means no upper bound)
+type lengthRule struct {
+	first, last int
+	length      SummaryLength
+}
+
+// lengthPolicy maps story rank to summary length
+type lengthPolicy []lengthRule
+
+// parseLengthPolicy parses a policy like "1:long,2-3:medium,4-:short"
+func parseLengthPolicy(value string) (lengthPolicy, error) {
+	var policy lengthPolicy
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		ranks, preset, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule %q, want <ranks>:<preset>", part)
+		}
+		length, ok := summaryLengthPresets[strings.TrimSpace(preset)]
+		if !ok {
+			return nil, fmt.Errorf("unknown length preset %q", preset)
+		}
+
+		rule := lengthRule{length: length}
+		firstValue, lastValue, isRange := strings.Cut(strings.TrimSpace(ranks), "-")
+		var err error
+		if rule.first, err = strconv.Atoi(firstValue); err != nil || rule.first < 1 {
+			return nil, fmt.Errorf("invalid rank in %q", part)
+		}
+		switch {
+		case !isRange:
+			rule.last = rule.first
+		case lastValue != "":
+			if rule.last, err = strconv.Atoi(lastValue); err != nil || rule.last < rule.first {
+				return nil, fmt.Errorf("invalid rank range in %q", part)
+			}
+		}
+		policy = append(policy, rule)
+	}
+	return policy, nil
+}
+
+// forRank returns the length for the story at rank (1-based), falling back
+// to def when no rule matches
+func (p lengthPolicy) forRank(rank int, def SummaryLength) SummaryLength {
+	for _, rule := range p {
+		if rank >= rule.first && (rule.last == 0 || rank <= rule.last) {
+			return rule.length
+		}
+	}
+	return def
+}
+
+// lengthSettingsFromEnv reads the global SUMMARY_LENGTH preset and the
+// rank-based SUMMARY_LENGTH_POLICY
+func lengthSettingsFromEnv() (SummaryLength, lengthPolicy, error) {
+	var def SummaryLength
+	if name := os.Getenv("SUMMARY_LENGTH"); name != "" {
+		var ok bool
+		if def, ok = summaryLengthPresets[name]; !ok {
+			return def, nil, fmt.Errorf("unknown SUMMARY_LENGTH preset %q", name)
+		}
+	}
+	policy, err := parseLengthPolicy(os.Getenv("SUMMARY_LENGTH_POLICY"))
+	if err != nil {
+		return def, nil, fmt.Errorf("SUMMARY_LENGTH_POLICY: %w", err)
+	}
+	return def, policy, nil
+}
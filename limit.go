@@ -0,0 +1,50 @@
+package main
+
+import "log"
+
+// defaultLimitMinScore is the score a story must beat to count towards a
+// bigger batch when SUMMARY_LIMIT_MIN_SCORE is unset
+const defaultLimitMinScore = 1000
+
+// summaryLimitBounds reads SUMMARY_LIMIT_MIN and SUMMARY_LIMIT_MAX, each
+// defaulting to summaryLimit, so the limit stays fixed unless they're set
+func summaryLimitBounds() (lo, hi int) {
+	lo = max(envInt("SUMMARY_LIMIT_MIN", summaryLimit), 1)
+	hi = envInt("SUMMARY_LIMIT_MAX", summaryLimit)
+	if hi < lo {
+		log.Printf("SUMMARY_LIMIT_MAX=%d is below SUMMARY_LIMIT_MIN=%d, using %d", hi, lo, lo)
+		hi = lo
+	}
+	return lo, hi
+}
+
+// computeDynamicLimit returns how many stories to summarize: one for each
+// story scoring above minScore, kept between SUMMARY_LIMIT_MIN and
+// SUMMARY_LIMIT_MAX. Sources without scores (every Score is 0) can't tell
+// a busy day from a slow one, so they get summaryLimit within the same bounds
+func computeDynamicLimit(stories []Story, minScore int) int {
+	lo, hi := summaryLimitBounds()
+	count, scored := 0, false
+	for _, story := range stories {
+		if story.Score != 0 {
+			scored = true
+		}
+		if story.Score > minScore {
+			count++
+		}
+	}
+	if !scored {
+		count = summaryLimit
+	}
+	return min(max(count, lo), hi)
+}
+
+// runSummaryLimit is computeDynamicLimit with SUMMARY_LIMIT_MIN_SCORE
+func runSummaryLimit(stories []Story) int {
+	return computeDynamicLimit(stories, limitMinScore())
+}
+
+// limitMinScore reads SUMMARY_LIMIT_MIN_SCORE
+func limitMinScore() int {
+	return envInt("SUMMARY_LIMIT_MIN_SCORE", defaultLimitMinScore)
+}
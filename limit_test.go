@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestComputeDynamicLimit(t *testing.T) {
+	scored := func(scores ...int) []Story {
+		var stories []Story
+		for _, score := range scores {
+			stories = append(stories, Story{Score: score})
+		}
+		return stories
+	}
+	for _, tc := range []struct {
+		name     string
+		min, max string
+		stories  []Story
+		want     int
+	}{
+		{name: "bounds unset", stories: scored(5000, 4000, 3000, 2000, 1500, 1200, 1100), want: 5},
+		{name: "busy day", min: "3", max: "8", stories: scored(5000, 4000, 3000, 2000, 1500, 1200, 200), want: 6},
+		{name: "capped", min: "3", max: "8", stories: scored(9000, 8000, 7000, 6000, 5000, 4000, 3000, 2000, 1500, 1200), want: 8},
+		{name: "slow day", min: "3", max: "8", stories: scored(1200, 900, 40, 10), want: 3},
+		{name: "unscored", min: "3", max: "8", stories: make([]Story, 12), want: 5},
+		{name: "unscored above max", min: "2", max: "4", stories: make([]Story, 12), want: 4},
+		{name: "max below min", min: "6", max: "4", stories: scored(100), want: 6},
+	} {
+		t.Setenv("SUMMARY_LIMIT_MIN", tc.min)
+		t.Setenv("SUMMARY_LIMIT_MAX", tc.max)
+		if got := computeDynamicLimit(tc.stories, 1000); got != tc.want {
+			t.Errorf("%s: computeDynamicLimit() = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
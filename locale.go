@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// dateNames holds the weekday (Sunday first) and month (January first)
+// names for a locale
+type dateNames struct {
+	weekdays [7]string
+	months   [12]string
+}
+
+// localeDateNames is the embedded translation table for DATE_LOCALE
+var localeDateNames = map[string]dateNames{
+	"de": {
+		weekdays: [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		months:   [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+	},
+	"fr": {
+		weekdays: [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		months:   [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+	},
+	"es": {
+		weekdays: [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		months:   [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+	},
+	"ja": {
+		weekdays: [7]string{"日", "月", "火", "水", "木", "金", "土"},
+	},
+}
+
+// localizeDate formats t for the date header in the given locale. English
+// (and any unsupported locale) keeps the original "January 2, 2006" format
+func localizeDate(t time.Time, locale string) string {
+	names, ok := localeDateNames[locale]
+	if !ok {
+		return t.Format("January 2, 2006")
+	}
+	weekday := names.weekdays[t.Weekday()]
+	month := names.months[t.Month()-1]
+	switch locale {
+	case "de":
+		return fmt.Sprintf("%s, %d. %s %d", weekday, t.Day(), month, t.Year())
+	case "fr":
+		return fmt.Sprintf("%s %d %s %d", weekday, t.Day(), month, t.Year())
+	case "es":
+		return fmt.Sprintf("%s, %d de %s de %d", weekday, t.Day(), month, t.Year())
+	case "ja":
+		return fmt.Sprintf("%d年%d月%d日(%s)", t.Year(), int(t.Month()), t.Day(), weekday)
+	}
+	return t.Format("January 2, 2006")
+}
@@ -3,10 +3,13 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,29 +19,76 @@ import (
 
 // Story represents a Reddit news story
 type Story struct {
-	Title string
-	Link  string
+	Title  string
+	Link   string
+	Author string
+	// ArticleURL is the article a Reddit post links out to, when Link is
+	// the post's permalink
+	ArticleURL string
+	Score      int // 0 when the source doesn't report scores
+	Published  time.Time
+	Revisited  bool
+	Trending   bool // in the top listing for the hour, day and week
+	ImagePost  bool // links to an image or gallery rather than an article
+
+	// Pinned stories are added manually with the pin subcommand
+	Pinned      bool
+	SkipSummary bool
+
+	// CompanyInfo is the company behind the link's domain, when CLEARBIT_API_KEY is set
+	CompanyInfo *ClearbitCompany
 }
 
 // SlackPayload defines the message format for Slack webhook
 type SlackPayload struct {
-	Text string `json:"text"`
+	Text    string `json:"text"`
+	IconURL string `json:"icon_url,omitempty"`
 }
 
 // Constants
 const (
-	redditRSS    = "https://www.reddit.com/r/news/top/.rss?t=day"
-	hfModelURL   = "https://api-inference.huggingface.co/models/facebook/bart-large-cnn"
-	summaryLimit = 5
+	redditRSSPath = "/r/news/top/.rss?t=day"
+	hfBaseURL     = "https://api-inference.huggingface.co"
+	hfModelPath   = "/models/facebook/bart-large-cnn"
+	summaryLimit  = 5
 )
 
 func main() {
-	// Load environment variables from .env
-	err := godotenv.Load()
-	if err != nil {
+	// Load environment variables from .env files
+	if loaded := loadEnvFiles(); len(loaded) == 0 {
 		log.Println("No .env file found — assuming environment variables are already set.")
 	}
 
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "pin":
+			runPinCommand(os.Args[2:])
+			return
+		case "explain":
+			runExplainCommand(os.Args[2:])
+			return
+		}
+	}
+
+	streamLogs := flag.Bool("stream-logs", false, "write per-story logs as they happen instead of grouping them at the end of the run")
+	replay := flag.String("replay", "", "print the stories a run would select from a saved feed snapshot, without posting or saving anything")
+	requeue := flag.String("requeue", "", "retry the stories in a dead letter file on this run")
+	since := flag.String("since", "", "only consider stories published since a duration ago (e.g. 3h), an RFC3339 timestamp, or \"last\" for the previous successful run")
+	backfill := flag.Bool("backfill", false, "summarize past top stories between --from and --to (at most BACKFILL_MAX_PER_MONTH per month) into BACKFILL_OUTPUT instead of posting")
+	from := flag.String("from", "", "first day to backfill (YYYY-MM-DD)")
+	to := flag.String("to", "", "last day to backfill (YYYY-MM-DD)")
+	flag.Parse()
+
+	if *backfill {
+		runBackfill(*from, *to, *streamLogs)
+		return
+	}
+	if *replay != "" {
+		runReplay(*replay, *since)
+		return
+	}
+
 	// Get API credentials
 	slackWebhook := os.Getenv("SLACK_WEBHOOK_URL")
 	hfAPIKey := os.Getenv("HUGGINGFACE_API_KEY")
@@ -47,120 +97,772 @@ func main() {
 		log.Fatal("Missing SLACK_WEBHOOK_URL or HUGGINGFACE_API_KEY in environment")
 	}
 
-	// Send the date as the first Slack message
-	currentDate := time.Now().Format("🗓️ January 2, 2006")
-	err = postToSlack(slackWebhook, currentDate)
+	window, hasWindow, err := postWindowFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid posting window: %v", err)
+	}
+	quiet, err := quietHoursFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid quiet hours: %v", err)
+	}
+
+	// Load persisted state from previous runs
+	statePath := stateFilePath()
+	state, err := loadState(statePath)
+	if err != nil {
+		log.Printf("Error loading state file %s: %v", statePath, err)
+	}
+	runStartedAt := time.Now()
+
+	// Report configuration changes since the previous run
+	checkConfigChange(&state, configSnapshot(map[string]string{"since": *since}))
+
+	cutoff, err := resolveSince(*since, state.LastRunAt)
+	if err != nil {
+		log.Fatalf("Invalid --since value: %v", err)
+	}
+
+	// Connect to the seen-story store, if one is configured
+	store, err := openStore()
 	if err != nil {
-		log.Fatalf("Error posting date to Slack: %v", err)
+		log.Fatalf("Failed to open story store: %v", err)
+	}
+	if redisStore, ok := store.(*RedisStore); ok {
+		if migrated, err := redisStore.migrateSeenSet(); err != nil {
+			log.Printf("Error migrating %s: %v", legacySeenStoriesKey, err)
+		} else if migrated > 0 {
+			log.Printf("Migrated %d stories from %s to %s", migrated, legacySeenStoriesKey, postedStoriesKey)
+		}
+	}
+
+	// Rotate the fetch User-Agent, if a pool is configured
+	if path := os.Getenv("USER_AGENTS_FILE"); path != "" {
+		userAgents, err = loadUserAgents(path)
+		if err != nil {
+			log.Fatalf("Failed to load user agents: %v", err)
+		}
 	}
 
 	// Fetch top Reddit news stories
-	stories, err := fetchTopStories(summaryLimit)
+	stories, err := fetchTopStories("")
 	if err != nil {
 		log.Fatalf("Failed to fetch stories: %v", err)
 	}
 
+	// Drop stories that are too old, image posts (if configured), repeats
+	// and recently posted ones
+	stories = selectStories(stories, selectionStages(cutoff, store), nil)
+
+	// Alert on unusual spikes or dry spells in new stories
+	checkStoryVolume(&state, len(stories))
+
+	// Alert on fast-rising stories before the batch is cut to the top few
+	if threshold, ok := velocityAlertThreshold(); ok {
+		alertFastStories(&state, stories, threshold)
+	}
+
+	// More stories on busy days, when the source reports scores
+	limit := runSummaryLimit(stories)
+	if limit != summaryLimit {
+		log.Printf("Summary limit %d (SUMMARY_LIMIT_MIN..MAX, stories above %d points)", limit, limitMinScore())
+	}
+	if len(stories) > limit {
+		stories = stories[:limit]
+	}
+
+	// Badge stories with sustained importance
+	if subreddit := os.Getenv("TRENDING_SUBREDDIT"); subreddit != "" {
+		if err := markTrending(stories, subreddit); err != nil {
+			log.Printf("Error checking trending stories: %v", err)
+		}
+	}
+
+	// Company details for stories linking to a company's site
+	if apiKey := os.Getenv("CLEARBIT_API_KEY"); apiKey != "" {
+		enrichStories(stories, apiKey)
+	}
+
+	// Pinned stories go at the top of the digest
+	pruneExpiredPins(&state, pinExpiry())
+	stories = append(pinnedStories(state.Pins), stories...)
+	state.Pins = nil
+
+	// Stories held back by the posting window on an earlier run
+	pending := dropStalePending(state.PendingPosts, maxPendingHold(), runStartedAt)
+	state.PendingPosts = nil
+	holding := make(map[string]PendingPost)
+	for _, p := range pending {
+		holding[p.Story.Link] = p
+	}
+
+	// Slack posts that failed on earlier runs, plus any requeued by hand
+	retried := state.FailedPosts
+	state.FailedPosts = nil
+	if *requeue != "" {
+		requeued, err := readDeadLetters(*requeue)
+		if err != nil {
+			log.Fatalf("Failed to read dead letter file: %v", err)
+		}
+		requeued = requeuable(requeued, retried, store)
+		log.Printf("Requeued %d stories from %s", len(requeued), *requeue)
+		retried = append(retried, requeued...)
+	}
+	deadLetters := newDeadLetterQueue(retried)
+	carried := append(pending, failedAsPending(retried)...)
+	stories = withoutPending(stories, carried)
+
+	// Which story fields the summarizer sees
+	var legacyInput bool
+	summaryInput, legacyInput, err = loadSummaryInputTemplate()
+	if err != nil {
+		log.Fatalf("Invalid SUMMARY_INPUT_TEMPLATE: %v", err)
+	}
+	if legacyInput {
+		log.Println("Using the deprecated \"title - link\" summarizer input; set SUMMARY_INPUT_TEMPLATE (e.g. {{.Title}}) to keep URL slugs out of summaries.")
+	}
+
+	// Publishers whose stories mustn't be summarized
+	if _, _, err := checkSummarizationPermission(""); err != nil {
+		log.Fatalf("Failed to load prohibited domains: %v", err)
+	}
+
+	// Summarization endpoints, tried in order until one succeeds
+	hfEndpoints = newEndpointPool(hfBaseURLs())
+
+	// Optional content-safety filter for summaries
+	if os.Getenv("SAFETY_FILTER") == "true" {
+		contentFilter, err = newSafetyFilter(os.Getenv("SAFETY_BLOCKLIST_FILE"))
+		if err != nil {
+			log.Fatalf("Failed to load safety filter: %v", err)
+		}
+	}
+
+	// Slack throttling settings for the posting phase
+	maxPosts := envInt("SLACK_MAX_POSTS_PER_RUN", 0)
+	postDelay := time.Duration(envInt("SLACK_MIN_INTER_POST_DELAY_MS", 500)) * time.Millisecond
+
+	summaries := make([]string, len(stories))
 	var wg sync.WaitGroup
 
-	// Launch goroutines for each story
+	// Per-story logs, grouped at the end of the run
+	storyLogs := newRunLog(*streamLogs)
 	for _, story := range stories {
+		if note := priorityNote(story, priorityDomainsFromEnv()); note != "" {
+			storyLogs.story(story).Printf("%s", note)
+		}
+	}
+
+	// Summary length, globally or by story rank
+	defaultLength, lengthByRank, err := lengthSettingsFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid summary length settings: %v", err)
+	}
+
+	// Launch goroutines to summarize each story
+	for i, story := range stories {
 		wg.Add(1)
-		go func(s Story) {
+		go func(i int, s Story) {
 			defer wg.Done()
-			processStory(s, hfAPIKey, slackWebhook)
-		}(story)
+			length := lengthByRank.forRank(i+1, defaultLength)
+			logs := storyLogs.story(s)
+			summaries[i] = processStory(s, hfAPIKey, length, logs)
+			// Stories without a summary go no further
+			if summaries[i] == "" {
+				logs.finish()
+			}
+		}(i, story)
 	}
 
 	// Wait for all summaries to be processed
 	wg.Wait()
+	stories, summaries = insertPending(stories, summaries, carried)
+
+	// Outside the posting window, either wait for it or hold the summaries
+	// for the next run
+	if hasWindow && !window.contains(time.Now()) {
+		if os.Getenv("POST_WAIT_FOR_WINDOW") == "true" {
+			opens := window.nextOpen(time.Now())
+			log.Printf("Outside posting window — waiting until %s", opens.Format(time.RFC3339))
+			time.Sleep(time.Until(opens))
+		} else {
+			// Retried posts stay failed posts, keeping their attempts
+			held := 0
+			for i, summary := range summaries {
+				if summary == "" {
+					continue
+				}
+				if deadLetters.retrying(stories[i].Link) {
+					deadLetters.keep(&state, stories[i].Link)
+				} else {
+					slackOnly := holding[stories[i].Link].SlackOnly
+					state.PendingPosts = append(state.PendingPosts, PendingPost{
+						Story:     stories[i],
+						Summary:   summary,
+						SlackOnly: slackOnly,
+						HeldAt:    heldSince(holding, stories[i].Link, runStartedAt),
+					})
+				}
+				storyLogs.story(stories[i]).setStatus("held")
+				storyLogs.story(stories[i]).finish()
+				held++
+			}
+			state.LastRunAt = runStartedAt
+			if err := saveState(statePath, state); err != nil {
+				log.Fatalf("Error saving state file %s: %v", statePath, err)
+			}
+			log.Printf("Outside posting window — holding %d stories for the next run", held)
+			storyLogs.flush()
+			return
+		}
+	}
+
+	// Send the date as the first Slack message, unless Slack is in its
+	// quiet hours. The date is the one in TIMEZONE, as in the digest
+	loc, err := botLocation()
+	if err != nil {
+		log.Fatalf("Invalid TIMEZONE: %v", err)
+	}
+	postingAt := time.Now().In(loc)
+	slackQuiet := quiet.active("slack", postingAt)
+	if !slackQuiet {
+		currentDate := "🗓️ " + localizeDate(postingAt, os.Getenv("DATE_LOCALE"))
+		err = postToSlack(slackWebhook, currentDate)
+		if err != nil {
+			log.Fatalf("Error posting date to Slack: %v", err)
+		}
+	}
+
+	// Pinning the top story needs the bot token, since webhooks don't
+	// return the posted message's timestamp
+	slackBotToken := os.Getenv("SLACK_BOT_TOKEN")
+	slackChannel := os.Getenv("SLACK_CHANNEL_ID")
+	pinTopStory := os.Getenv("SLACK_PIN_TOP_STORY") == "true" && !slackQuiet
+	if pinTopStory && (slackBotToken == "" || slackChannel == "") {
+		log.Println("SLACK_PIN_TOP_STORY requires SLACK_BOT_TOKEN and SLACK_CHANNEL_ID — not pinning.")
+		pinTopStory = false
+	}
+	var pinWG sync.WaitGroup
+	var pinnedTS string // set once the pin goes through
+
+	// Other chat destinations
+	zulip, zulipEnabled := zulipConfigFromEnv()
+	discordWebhook := os.Getenv("DISCORD_WEBHOOK_URL")
+	apns, err := apnsSenderFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid APNs settings: %v", err)
+	}
+
+	// Stories queued during quiet hours go out as one roundup per destination
+	roundups := map[string]func([]QueuedPost) error{
+		"slack": func(posts []QueuedPost) error { return postToSlack(slackWebhook, formatSlackRoundup(posts)) },
+	}
+	if zulipEnabled {
+		roundups["zulip"] = func(posts []QueuedPost) error { return zulip.post(formatZulipRoundup(posts)) }
+	}
+	if discordWebhook != "" {
+		roundups["discord"] = func(posts []QueuedPost) error { return postDiscordRoundup(discordWebhook, posts) }
+	}
+	flushQueues(&state, quiet, store, roundups)
+
+	// Post summaries one at a time, in feed order
+	var digest []digestEntry
+	posted := 0
+	for i, summary := range summaries {
+		if summary == "" {
+			continue
+		}
+		logs := storyLogs.story(stories[i])
+		message := formatSlackMessage(stories[i], summary)
+		// Retried posts, and held ones the other destinations already got,
+		// only go to Slack
+		hold, isHeld := holding[stories[i].Link]
+		slackOnly := deadLetters.retrying(stories[i].Link) || hold.SlackOnly
+		var quietFor []string
+		slackFailed, delivered := false, false
+		pinning := false // the pin goroutine finishes the story's log
+		switch {
+		case slackQuiet:
+			quietFor = append(quietFor, "slack")
+		case maxPosts > 0 && posted >= maxPosts:
+			// The cap only limits Slack; the other destinations still get the
+			// story, and retried and held posts wait for the next run
+			logs.Printf("Reached SLACK_MAX_POSTS_PER_RUN (%d), not posting to Slack", maxPosts)
+			if deadLetters.retrying(stories[i].Link) {
+				deadLetters.keep(&state, stories[i].Link)
+			} else if isHeld {
+				state.PendingPosts = append(state.PendingPosts, PendingPost{
+					Story:     stories[i],
+					Summary:   summary,
+					SlackOnly: true,
+					HeldAt:    heldSince(holding, stories[i].Link, runStartedAt),
+				})
+			}
+			if slackOnly {
+				logs.setStatus("held")
+				logs.finish()
+				continue
+			}
+		default:
+			if posted > 0 {
+				time.Sleep(postDelay)
+			}
+			var err error
+			if pinTopStory && !stories[i].Pinned && !slackOnly && !isHeld {
+				// The first story fetched this run is the highest ranked one
+				pinTopStory = false
+				var ts string
+				err = logs.deliver("slack", func() error {
+					var err error
+					ts, err = postSlackMessage(slackBotToken, slackChannel, message, storyIconURL(stories[i]))
+					return err
+				})
+				if err == nil {
+					previousTS := state.PinnedTS
+					pinning = true
+					pinWG.Add(1)
+					go func() {
+						defer pinWG.Done()
+						defer logs.finish()
+						if err := pinSlackMessage(slackBotToken, slackChannel, ts, previousTS); err != nil {
+							logs.Fail("slack pin", err)
+							return
+						}
+						pinnedTS = ts
+					}()
+				}
+			} else {
+				err = logs.deliver("slack", func() error {
+					return postSlackPayload(slackWebhook, SlackPayload{Text: message, IconURL: storyIconURL(stories[i])})
+				})
+			}
+			if err != nil {
+				// Kept for the next run's retry, but the other destinations
+				// still get the story now
+				logDeliveryFailure(logs, "slack", err)
+				deadLetters.fail(&state, stories[i], summary, err)
+				slackFailed = true
+			} else {
+				posted++
+				delivered = true
+			}
+		}
+		if zulipEnabled && !slackOnly && quiet.active("zulip", postingAt) {
+			quietFor = append(quietFor, "zulip")
+		} else if zulipEnabled && !slackOnly {
+			err := logs.deliver("zulip", func() error { return zulip.post(formatZulipMessage(stories[i], summary)) })
+			if err != nil {
+				logDeliveryFailure(logs, "zulip", err)
+			} else {
+				delivered = true
+			}
+		}
+		if discordWebhook != "" && !slackOnly && quiet.active("discord", postingAt) {
+			quietFor = append(quietFor, "discord")
+		} else if discordWebhook != "" && !slackOnly {
+			err := logs.deliver("discord", func() error { return postToDiscord(discordWebhook, buildDiscordEmbed(stories[i], summary)) })
+			if err != nil {
+				logDeliveryFailure(logs, "discord", err)
+			} else {
+				delivered = true
+			}
+		}
+		if apns != nil && !slackOnly {
+			if err := logs.deliver("apns", func() error { return apns.Send(stories[i], summary) }); err != nil {
+				logDeliveryFailure(logs, "apns", err)
+			} else {
+				delivered = true
+			}
+		}
+		if !delivered && len(quietFor) == 0 {
+			if !pinning {
+				logs.finish()
+			}
+			continue
+		}
+		digest = append(digest, newDigestEntry(stories[i], summary))
+		// A story still owed a Slack post stays out of the store until its
+		// retry goes through
+		if store != nil && !slackFailed {
+			if err := store.SaveStory(stories[i].Link); err != nil {
+				logs.Printf("Error saving to store: %v", err)
+			}
+			if knownAuthor(stories[i].Author) {
+				if err := store.RecordAuthorPost(stories[i].Author, stories[i].Link); err != nil {
+					logs.Printf("Error recording author: %v", err)
+				}
+			}
+		}
+		// Queued after the store is updated, so a later post of the same
+		// story is what drops it from the roundup
+		for _, destination := range quietFor {
+			queuePost(&state, destination, stories[i], summary)
+			logs.Printf("Queued for %s until its quiet hours end", destination)
+		}
+		if !pinning {
+			logs.finish()
+		}
+	}
+
+	// Let any in-flight pin operations finish before exiting
+	pinWG.Wait()
+	if pinnedTS != "" {
+		state.PinnedTS = pinnedTS
+	}
+	storyLogs.flush()
+	deadLetters.flush()
+
+	// Close with a one-line report of the run
+	if os.Getenv("RUN_SUMMARY") == "true" {
+		summaryWebhook := os.Getenv("SUMMARY_WEBHOOK_URL")
+		if summaryWebhook == "" && !slackQuiet {
+			summaryWebhook = slackWebhook
+		}
+		if summaryWebhook != "" {
+			report := formatRunSummary(posted, len(stories), time.Since(runStartedAt), storyLogs.stats())
+			if err := postToSlack(summaryWebhook, report); err != nil {
+				log.Printf("Error posting run summary: %v", err)
+			}
+		}
+	}
+
+	// Publish the static HTML archive
+	if dir := os.Getenv("HTML_OUTPUT_DIR"); dir != "" {
+		if err := writeHTMLDigest(dir, runStartedAt, digest); err != nil {
+			log.Printf("Error writing HTML digest: %v", err)
+		}
+	}
+
+	if endpoint := hfEndpoints.Chosen(); endpoint != "" {
+		log.Printf("Summarization endpoint used: %s", endpoint)
+	}
+
+	// Record this run so the next one can pick up where it left off
+	state.LastRunAt = runStartedAt
+	if err := saveState(statePath, state); err != nil {
+		log.Printf("Error saving state file %s: %v", statePath, err)
+	}
+}
+
+// resolveSince turns the --since flag into a cutoff time. A zero time means
+// no cutoff should be applied
+func resolveSince(value string, lastRunAt time.Time) (time.Time, error) {
+	switch value {
+	case "":
+		return time.Time{}, nil
+	case "last":
+		if lastRunAt.IsZero() {
+			log.Println("No previous run recorded — considering the full feed window.")
+		}
+		return lastRunAt, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, value)
 }
 
-// processStory handles summarization and Slack posting for a single story
-func processStory(story Story, hfAPIKey, slackWebhook string) {
-	// Combine title and link for summarization input
-	text := fmt.Sprintf("%s - %s", story.Title, story.Link)
+// processStory summarizes a single story, returning an empty string if
+// summarization failed
+func processStory(story Story, hfAPIKey string, length SummaryLength, logs *storyLog) string {
+	if story.SkipSummary {
+		return story.Link
+	}
+
+	// Publishers that prohibit automated summaries get the link and a note
+	if allowed, reason, _ := checkSummarizationPermission(storyDomain(articleLink(story))); !allowed {
+		logs.Printf("Not summarizing: %s prohibits it (%s)", storyDomain(articleLink(story)), reason)
+		return notPermittedNote + " " + story.Link
+	}
+
+	// Simple titles are their own summary, saving API quota
+	if threshold, ok := titlePassthroughEase(); ok && computeReadingLevel(story.Title) > threshold {
+		return story.Title
+	}
+
+	// Build the summarization input. An image's link says nothing about it,
+	// so image posts are summarized from the title alone
+	text, err := buildSummaryInput(story, logs)
+	if err != nil {
+		logs.Fail("summarize", err)
+		return ""
+	}
+	if story.ImagePost {
+		text = story.Title
+		logs.setInput(text)
+	}
 
 	// Summarize the story using Hugging Face
-	summary, err := summarizeWithHuggingFace(hfAPIKey, text)
+	if length.Name != "" {
+		logs.Printf("Summary length: %s (%d-%d tokens)", length.Name, length.MinLength, length.MaxLength)
+	}
+	var summary string
+	err = logs.stage("summarize", func() error {
+		var err error
+		if count := summaryCandidates(); count > 1 {
+			summary, err = summarizeBest(hfAPIKey, text, story.Title, length, count, logs)
+		} else {
+			summary, err = summarizeText(hfAPIKey, text, length)
+		}
+		return err
+	})
+	if err != nil && os.Getenv("FAST_EXTRACTIVE_FALLBACK") == "true" {
+		if extract := (ExtractiveSummarizer{Sentences: envInt("EXTRACTIVE_SENTENCES", 3)}).Summarize(text); extract != "" {
+			logs.Printf("summarize failed, using key sentences instead: %v", err)
+			return applySafetyFilter(story, extractiveLabel+" "+extract, logs)
+		}
+	}
 	if err != nil {
-		log.Printf("Error summarizing '%s': %v", story.Title, err)
-		return
+		logs.Fail("summarize", err)
+		return ""
 	}
+	return applySafetyFilter(story, postProcessSummary(summary), logs)
+}
 
-	// Format Slack message (no separator line, no links)
-	message := fmt.Sprintf("*Title:* %s\n> %s", story.Title, summary)
+// displayTitle returns the story title with any status markers
+func displayTitle(story Story) string {
+	title := story.Title
+	if story.ImagePost {
+		title = "[Image Post] " + title
+	}
+	if story.Revisited {
+		title = "[Revisited] " + title
+	}
+	if story.Trending {
+		title = "🔥 Trending " + title
+	}
+	if story.Pinned {
+		title = "📌 " + title
+	}
+	return title
+}
 
-	// Send to Slack
-	err = postToSlack(slackWebhook, message)
-	if err != nil {
-		log.Printf("Error posting to Slack: %v", err)
+// articleLink returns the story's article, which for a Reddit post is where
+// the post links out to rather than the post itself
+func articleLink(story Story) string {
+	if story.ArticleURL != "" {
+		return story.ArticleURL
 	}
+	return story.Link
 }
 
-// fetchTopStories pulls N top stories from Reddit's RSS feed
-func fetchTopStories(limit int) ([]Story, error) {
-	fp := gofeed.NewParser()
-	feed, err := fp.ParseURL(redditRSS)
+// storyIconURL returns the logo of the story's company, used as the Slack
+// message's author image
+func storyIconURL(story Story) string {
+	if story.CompanyInfo == nil {
+		return ""
+	}
+	return story.CompanyInfo.Logo
+}
+
+// slackEscaper escapes the characters Slack reads as markup, so a title
+// like "<!channel>" can't ping anyone
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// formatSlackMessage formats a story for Slack (no separator line, no links).
+// Titles passed through as their own summary aren't repeated
+func formatSlackMessage(story Story, summary string) string {
+	title := slackEscaper.Replace(displayTitle(story))
+	if summary == story.Title {
+		return fmt.Sprintf("*Title:* %s", title)
+	}
+	return fmt.Sprintf("*Title:* %s\n> %s", title, slackEscaper.Replace(summary))
+}
+
+// loadEnvFiles loads .env, then .env.local, then .env.<BOT_ENV>, each
+// overriding the ones before it, and returns the files that were found
+func loadEnvFiles() []string {
+	var loaded []string
+	load := func(name string) {
+		if _, err := os.Stat(name); err != nil {
+			return
+		}
+		if err := godotenv.Overload(name); err != nil {
+			log.Printf("Error loading %s: %v", name, err)
+			return
+		}
+		loaded = append(loaded, name)
+	}
+	load(".env")
+	load(".env.local")
+	// BOT_ENV may itself come from one of the files above
+	if env := os.Getenv("BOT_ENV"); env != "" {
+		load(".env." + env)
+	}
+	return loaded
+}
+
+// envInt reads an integer environment variable, falling back to def when
+// the variable is unset or invalid
+func envInt(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("Invalid %s=%q, using default %d", key, value, def)
+		return def
+	}
+	return n
+}
+
+// fetchTopStories pulls the top stories from the configured feed (or Reddit
+// search, when a query is configured). A non-empty replay path reads a
+// saved snapshot instead
+func fetchTopStories(replay string) ([]Story, error) {
+	var data []byte
+	var format listingFormat
+	var err error
+	if replay != "" {
+		data, format, err = readSnapshot(replay)
+	} else {
+		if userAgents != nil {
+			fetchUserAgent = userAgents.Next()
+		}
+		data, format, err = fetchCandidateListing()
+		if err == nil {
+			saveSnapshot(data, format)
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	var stories []Story
-	for i, item := range feed.Items {
-		if i >= limit {
-			break
+	return parseListing(data, format)
+}
+
+// fetchCandidateListing downloads the raw listing of candidate stories
+func fetchCandidateListing() ([]byte, listingFormat, error) {
+	if query, subreddit, sort, ok := redditSearchFromEnv(); ok {
+		data, err := fetchRaw(redditSearchURL(query, subreddit, sort, searchCandidateLimit), "application/json")
+		return data, formatReddit, err
+	}
+
+	url := feedURL()
+	if isJSONFeed(url) {
+		data, err := fetchRaw(url, jsonFeedAccept)
+		return data, formatJSONFeed, err
+	}
+
+	data, err := fetchRaw(url, rssAccept)
+	if err != nil {
+		return nil, formatRSS, err
+	}
+	if gofeed.DetectFeedType(bytes.NewReader(data)) == gofeed.FeedTypeUnknown {
+		// Not a feed — the URL may be a website advertising one
+		discovered, err := discoverFeedFromHTML(data, url)
+		if err != nil {
+			return nil, formatRSS, fmt.Errorf("%s is not a feed (feed discovery: %v)", url, err)
 		}
-		stories = append(stories, Story{
-			Title: item.Title,
-			Link:  item.Link,
-		})
+		log.Printf("Discovered feed %s from %s", discovered, url)
+		data, err = fetchRaw(discovered, rssAccept)
 	}
-	return stories, nil
+	return data, formatRSS, err
 }
 
-// summarizeWithHuggingFace uses the Hugging Face inference API to summarize text
-func summarizeWithHuggingFace(apiKey, text string) (string, error) {
-	body, _ := json.Marshal(map[string]string{"inputs": text})
-
-	req, err := http.NewRequest("POST", hfModelURL, bytes.NewBuffer(body))
+// fetchRSSFeed downloads and parses an RSS or Atom feed into stories
+func fetchRSSFeed(url string) ([]Story, error) {
+	data, err := fetchRaw(url, rssAccept)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
+	return parseRSSFeed(data)
+}
 
-	client := &http.Client{Timeout: 40 * time.Second}
-	resp, err := client.Do(req)
+// parseRSSFeed parses an RSS or Atom feed into stories. Titles with invalid
+// UTF-8, which gofeed passes through from JSON feeds, get replacement
+// characters instead
+func parseRSSFeed(data []byte) ([]Story, error) {
+	fp := gofeed.NewParser()
+	feed, err := fp.Parse(bytes.NewReader(data))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var result []map[string]string
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+	var stories []Story
+	for _, item := range feed.Items {
+		story := Story{
+			Title:      strings.ToValidUTF8(item.Title, "\uFFFD"),
+			Link:       item.Link,
+			Author:     extractAuthor(item),
+			ArticleURL: redditOutboundLink(item),
+		}
+		if item.PublishedParsed != nil {
+			story.Published = *item.PublishedParsed
+		} else if item.UpdatedParsed != nil {
+			story.Published = *item.UpdatedParsed
+		}
+		stories = append(stories, story)
 	}
+	return stories, nil
+}
+
+// unknownAuthor stands in for an RSS item without any author field
+const unknownAuthor = "[unknown]"
 
-	if len(result) > 0 && result[0]["summary_text"] != "" {
-		return result[0]["summary_text"], nil
+// extractAuthor returns the first non-empty author of an RSS item, checking
+// the fields feeds use for it in priority order
+func extractAuthor(item *gofeed.Item) string {
+	if item.Author != nil && item.Author.Name != "" {
+		return item.Author.Name
+	}
+	for _, person := range item.Authors {
+		if person != nil && person.Name != "" {
+			return person.Name
+		}
+	}
+	for _, creator := range item.Extensions["dc"]["creator"] {
+		if creator.Value != "" {
+			return creator.Value
+		}
+	}
+	if item.DublinCoreExt != nil {
+		for _, creator := range item.DublinCoreExt.Creator {
+			if creator != "" {
+				return creator
+			}
+		}
 	}
+	return unknownAuthor
+}
+
+// deletedAuthor is Reddit's author for posts whose account was deleted
+const deletedAuthor = "[deleted]"
 
-	return "Summary unavailable", nil
+// knownAuthor reports whether author identifies someone, so it can be
+// counted towards per-author limits. Deleted accounts are all "[deleted]",
+// so they don't
+func knownAuthor(author string) bool {
+	return author != "" && author != unknownAuthor && strings.TrimPrefix(author, "/u/") != deletedAuthor
 }
 
 // postToSlack sends a formatted message to the Slack webhook
 func postToSlack(webhookURL, message string) error {
-	payload := SlackPayload{Text: message}
+	return postSlackPayload(webhookURL, SlackPayload{Text: message})
+}
+
+// postSlackPayload sends a full webhook payload to Slack
+func postSlackPayload(webhookURL string, payload SlackPayload) error {
 	data, _ := json.Marshal(payload)
 
-	resp, err := http.Post(webhookURL, "application/json", bytes.NewBuffer(data))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+	return withRetry(DestinationSlack, func() error {
+		req, err := http.NewRequest("POST", webhookURL, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if err := signWebhookRequest(req, data); err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return &NotifyError{Destination: "slack", Err: err}
+		}
+		defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("Slack responded with status: %v", resp.Status)
-	}
-	return nil
+		if resp.StatusCode != 200 {
+			return notifyResponseError("slack", resp)
+		}
+		return nil
+	})
 }
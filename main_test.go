@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzParseRSSFeed feeds malformed and unusual feeds to parseRSSFeed. Seeds
+// (real captured feeds plus inputs that broke past runs) are in
+// testdata/fuzz/FuzzParseRSSFeed, where `go test -fuzz` also saves any
+// failing input so it keeps being checked
+func FuzzParseRSSFeed(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		stories, err := parseRSSFeed(data)
+		if err != nil {
+			return
+		}
+		for _, story := range stories {
+			if !utf8.ValidString(story.Title) {
+				t.Errorf("title is not valid UTF-8: %q", story.Title)
+			}
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// imageHosts serve bare images rather than pages
+var imageHosts = map[string]bool{
+	"i.imgur.com": true,
+	"i.redd.it":   true,
+}
+
+// imageExtensions mark a link as pointing straight at an image
+var imageExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+	".webp": true,
+}
+
+// isImageURL reports whether link points at an image instead of an article
+func isImageURL(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return imageHosts[u.Hostname()] || imageExtensions[strings.ToLower(path.Ext(u.Path))]
+}
+
+// markImagePosts flags stories linking to images. Reddit listings flag
+// their image and gallery posts when parsed
+func markImagePosts(stories []Story) {
+	for i := range stories {
+		if isImageURL(articleLink(stories[i])) {
+			stories[i].ImagePost = true
+		}
+	}
+}
+
+// filterImagePosts drops stories that link to images
+func filterImagePosts(stories []Story) []Story {
+	var kept []Story
+	for _, story := range stories {
+		if story.ImagePost {
+			log.Printf("Skipping image post: %s", story.Title)
+			continue
+		}
+		kept = append(kept, story)
+	}
+	return kept
+}
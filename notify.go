@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBodySnippet caps how much of a failed response body is kept
+const maxErrorBodySnippet = 512
+
+// NotifyError describes a failed delivery to a notification destination
+type NotifyError struct {
+	Destination string // e.g. "slack", "zulip"
+	StatusCode  int    // 0 when no response was received
+	Body        string // start of the response body, if any
+	Err         error  // underlying transport error, if any
+}
+
+func (e *NotifyError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Destination, e.Err)
+	}
+	msg := fmt.Sprintf("%s responded with status: %d %s", e.Destination, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+func (e *NotifyError) Unwrap() error { return e.Err }
+
+// Retryable reports whether the same delivery might succeed if tried again.
+// Transport failures, timeouts, rate limiting and server errors are
+// retryable; other client errors (bad payload, revoked webhook) are not
+func (e *NotifyError) Retryable() bool {
+	switch {
+	case e.StatusCode == 0:
+		return true
+	case e.StatusCode == http.StatusRequestTimeout, e.StatusCode == http.StatusTooManyRequests:
+		return true
+	case e.StatusCode >= 500:
+		return true
+	}
+	return false
+}
+
+// notifyResponseError builds a NotifyError from a non-success response
+func notifyResponseError(destination string, resp *http.Response) *NotifyError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySnippet))
+	return &NotifyError{
+		Destination: destination,
+		StatusCode:  resp.StatusCode,
+		Body:        strings.TrimSpace(string(body)),
+	}
+}
+
+// logDeliveryFailure records a failed post to destination, noting whether
+// the failure is transient or permanent
+func logDeliveryFailure(logs *storyLog, destination string, err error) {
+	stage := "post to " + destination
+	var ne *NotifyError
+	if errors.As(err, &ne) {
+		if ne.Retryable() {
+			stage += " (transient)"
+		} else {
+			stage += " (permanent)"
+		}
+	}
+	logs.Fail(stage, err)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNotifyErrorRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  NotifyError
+		want bool
+	}{
+		{"transport failure", NotifyError{Destination: "slack", Err: errors.New("connection reset")}, true},
+		{"400 bad payload", NotifyError{Destination: "slack", StatusCode: 400}, false},
+		{"403 revoked webhook", NotifyError{Destination: "slack", StatusCode: 403}, false},
+		{"404 deleted webhook", NotifyError{Destination: "discord", StatusCode: 404}, false},
+		{"408 request timeout", NotifyError{Destination: "zulip", StatusCode: 408}, true},
+		{"429 rate limited", NotifyError{Destination: "slack", StatusCode: 429}, true},
+		{"500 server error", NotifyError{Destination: "slack", StatusCode: 500}, true},
+		{"502 bad gateway", NotifyError{Destination: "discord", StatusCode: 502}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Retryable(); got != tt.want {
+				t.Errorf("Retryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
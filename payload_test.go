@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// payloadCase is one entry of testdata/payloads/corpus.json: a real-world
+// title and summary that has caused rendering trouble somewhere
+type payloadCase struct {
+	Name    string `json:"name"`
+	Story   Story  `json:"story"`
+	Summary string `json:"summary"`
+}
+
+// payloadSink renders a corpus entry as one destination's request body and
+// checks it against that service's documented limits. A new sink adds an
+// entry here and gets a golden file in testdata/payloads
+type payloadSink struct {
+	name   string
+	render func(story Story, summary string) any
+	check  func(payload any) error
+}
+
+var payloadSinks = []payloadSink{
+	{
+		name: "slack",
+		render: func(story Story, summary string) any {
+			return SlackPayload{Text: formatSlackMessage(story, summary), IconURL: storyIconURL(story)}
+		},
+		check: func(payload any) error {
+			// Slack truncates message text after 40,000 characters, and a
+			// stray < or > would become a link or a mention
+			text := payload.(SlackPayload).Text
+			if n := utf8.RuneCountInString(text); n > 40000 {
+				return fmt.Errorf("text is %d characters, over Slack's 40000", n)
+			}
+			if strings.ContainsAny(strings.ReplaceAll(text, "\n> ", "\n"), "<>") {
+				return fmt.Errorf("unescaped < or > in %q", text)
+			}
+			return nil
+		},
+	},
+	{
+		name: "discord",
+		render: func(story Story, summary string) any {
+			return discordPayload{Embeds: []DiscordEmbed{buildDiscordEmbed(story, summary)}}
+		},
+		check: func(payload any) error {
+			embed := payload.(discordPayload).Embeds[0]
+			total := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+			if embed.Footer != nil {
+				total += utf8.RuneCountInString(embed.Footer.Text)
+			}
+			switch {
+			case utf8.RuneCountInString(embed.Title) > discordTitleLimit:
+				return fmt.Errorf("embed title over %d characters", discordTitleLimit)
+			case utf8.RuneCountInString(embed.Description) > discordDescriptionLimit:
+				return fmt.Errorf("embed description over %d characters", discordDescriptionLimit)
+			case total > 6000:
+				return fmt.Errorf("embed text totals %d characters, over Discord's 6000", total)
+			}
+			return nil
+		},
+	},
+	{
+		name: "zulip",
+		render: func(story Story, summary string) any {
+			return formatZulipMessage(story, summary)
+		},
+		check: func(payload any) error {
+			if n := utf8.RuneCountInString(payload.(string)); n > 10000 {
+				return fmt.Errorf("message is %d characters, over Zulip's 10000", n)
+			}
+			return nil
+		},
+	},
+	{
+		name: "apns",
+		render: func(story Story, summary string) any {
+			return newAPNSPayload(story, summary)
+		},
+		check: func(payload any) error {
+			data, _ := json.Marshal(payload)
+			if len(data) > 4096 {
+				return fmt.Errorf("payload is %d bytes, over APNs' 4096", len(data))
+			}
+			return nil
+		},
+	},
+}
+
+func TestPayloadCorpus(t *testing.T) {
+	t.Setenv("DISCORD_EMBED_COLOR", "")
+	data, err := os.ReadFile(filepath.Join("testdata", "payloads", "corpus.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var corpus []payloadCase
+	if err := json.Unmarshal(data, &corpus); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sink := range payloadSinks {
+		t.Run(sink.name, func(t *testing.T) {
+			var golden bytes.Buffer
+			for _, c := range corpus {
+				payload := sink.render(c.Story, c.Summary)
+				if err := sink.check(payload); err != nil {
+					t.Errorf("%s: %v", c.Name, err)
+				}
+				// Unescaped HTML keeps the goldens readable; it's the same JSON
+				fmt.Fprintf(&golden, "== %s\n", c.Name)
+				encoder := json.NewEncoder(&golden)
+				encoder.SetEscapeHTML(false)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(payload); err != nil {
+					t.Fatalf("%s: %v", c.Name, err)
+				}
+			}
+			checkGolden(t, filepath.Join("payloads", sink.name+".golden"), golden.Bytes())
+		})
+	}
+}
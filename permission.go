@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// notPermittedNote replaces the summary of stories from prohibited domains
+const notPermittedNote = "[Summarization not permitted]"
+
+// ProhibitedDomain is a publisher that doesn't allow automated summaries
+type ProhibitedDomain struct {
+	Domain string `json:"domain"`
+	Reason string `json:"reason"`
+}
+
+var (
+	prohibitedOnce    sync.Once
+	prohibitedDomains []ProhibitedDomain
+	prohibitedErr     error
+)
+
+// loadProhibitedDomains reads PROHIBITED_DOMAINS_FILE, a JSON list of
+// {"domain", "reason"} objects
+func loadProhibitedDomains() ([]ProhibitedDomain, error) {
+	path := os.Getenv("PROHIBITED_DOMAINS_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var domains []ProhibitedDomain
+	if err := json.Unmarshal(data, &domains); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return domains, nil
+}
+
+// checkSummarizationPermission reports whether stories from domain may be
+// summarized, and if not, the publisher's reason. A listed domain also
+// covers its subdomains
+func checkSummarizationPermission(domain string) (bool, string, error) {
+	prohibitedOnce.Do(func() {
+		prohibitedDomains, prohibitedErr = loadProhibitedDomains()
+	})
+	if prohibitedErr != nil {
+		return false, "", prohibitedErr
+	}
+	domain = strings.ToLower(strings.TrimPrefix(domain, "www."))
+	for _, p := range prohibitedDomains {
+		listed := strings.ToLower(strings.TrimPrefix(p.Domain, "www."))
+		if domain == listed || strings.HasSuffix(domain, "."+listed) {
+			return false, p.Reason, nil
+		}
+	}
+	return true, "", nil
+}
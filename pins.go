@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+)
+
+// PinnedStory is a manually added story waiting for the next run
+type PinnedStory struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Link      string    `json:"link"`
+	Summarize bool      `json:"summarize,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// runPinCommand implements the `pin` subcommand for adding, listing and
+// removing pinned stories
+func runPinCommand(args []string) {
+	fs := flag.NewFlagSet("pin", flag.ExitOnError)
+	title := fs.String("title", "", "title of the story to pin")
+	link := fs.String("link", "", "link of the story to pin")
+	summarize := fs.Bool("summarize", false, "summarize the link like any other story")
+	list := fs.Bool("list", false, "list pending pinned stories")
+	remove := fs.String("remove", "", "remove the pinned story with this ID")
+	fs.Parse(args)
+
+	statePath := stateFilePath()
+	state, err := loadState(statePath)
+	if err != nil {
+		log.Fatalf("Error loading state file %s: %v", statePath, err)
+	}
+	pruneExpiredPins(&state, pinExpiry())
+
+	switch {
+	case *list:
+		if len(state.Pins) == 0 {
+			fmt.Println("No pinned stories.")
+		}
+		for _, pin := range state.Pins {
+			fmt.Printf("%s  %s  %s (pinned %s)\n", pin.ID, pin.Title, pin.Link, pin.CreatedAt.Format(time.RFC3339))
+		}
+		return
+	case *remove != "":
+		kept := state.Pins[:0]
+		for _, pin := range state.Pins {
+			if pin.ID != *remove {
+				kept = append(kept, pin)
+			}
+		}
+		if len(kept) == len(state.Pins) {
+			log.Fatalf("No pinned story with ID %s", *remove)
+		}
+		state.Pins = kept
+	case *title != "" && *link != "":
+		pin := PinnedStory{
+			ID:        newPinID(),
+			Title:     *title,
+			Link:      *link,
+			Summarize: *summarize,
+			CreatedAt: time.Now(),
+		}
+		state.Pins = append(state.Pins, pin)
+		fmt.Printf("Pinned %s: %s\n", pin.ID, pin.Title)
+	default:
+		log.Fatal("pin requires --title and --link, --list, or --remove <id>")
+	}
+
+	if err := saveState(statePath, state); err != nil {
+		log.Fatalf("Error saving state file %s: %v", statePath, err)
+	}
+}
+
+// pinExpiry returns how long a pin waits for a run before it is discarded
+func pinExpiry() time.Duration {
+	return time.Duration(envInt("PIN_EXPIRY_HOURS", 24)) * time.Hour
+}
+
+// pruneExpiredPins drops pins older than expiry
+func pruneExpiredPins(state *State, expiry time.Duration) {
+	kept := state.Pins[:0]
+	for _, pin := range state.Pins {
+		if time.Since(pin.CreatedAt) > expiry {
+			log.Printf("Pinned story %s expired: %s", pin.ID, pin.Title)
+			continue
+		}
+		kept = append(kept, pin)
+	}
+	state.Pins = kept
+}
+
+// pinnedStories converts pending pins into stories for the digest
+func pinnedStories(pins []PinnedStory) []Story {
+	var stories []Story
+	for _, pin := range pins {
+		stories = append(stories, Story{
+			Title:       pin.Title,
+			Link:        pin.Link,
+			Pinned:      true,
+			SkipSummary: !pin.Summarize,
+		})
+	}
+	return stories
+}
+
+// newPinID returns a short random identifier for a pin
+func newPinID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
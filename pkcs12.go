@@ -0,0 +1,621 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"math/bits"
+	"unicode/utf16"
+)
+
+// PKCS#12 (RFC 7292) is what Keychain Access and openssl export push
+// certificates as. The decoder below reads the certificate and private key
+// out of one, covering the encryption schemes those tools use: PBES2 with
+// AES (openssl 3), and SHA-1 with 3DES or 40-bit RC2 (Keychain, openssl
+// -legacy)
+
+var (
+	oidDataContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedDataContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+
+	oidKeyBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidPKCS8ShroudedKeyBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidX509Certificate     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+
+	oidPBEWithSHAAnd3KeyTripleDESCBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBEWithSHAAnd40BitRC2CBC      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}
+	oidPBES2                         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2                        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1                  = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256                = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES128CBC                     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC                     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC                     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidSHA1                          = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256                        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+)
+
+// errPKCS12Password is returned when the MAC or a decryption doesn't check
+// out, which nearly always means the password is wrong
+var errPKCS12Password = errors.New("wrong password or corrupt .p12 file")
+
+type pfxPDU struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue `asn1:"tag:0,explicit"`
+	Attributes asn1.RawValue `asn1:"optional"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// decodePKCS12 returns the certificate chain and private key in a .p12
+// file, with the certificate matching the key first
+func decodePKCS12(data []byte, password string) (tls.Certificate, error) {
+	der, err := berToDER(data)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("not a .p12 file: %w", err)
+	}
+	var pfx pfxPDU
+	if _, err := asn1.Unmarshal(der, &pfx); err != nil {
+		return tls.Certificate{}, fmt.Errorf("not a .p12 file: %w", err)
+	}
+	if pfx.Version != 3 || !pfx.AuthSafe.ContentType.Equal(oidDataContentType) {
+		return tls.Certificate{}, errors.New("unsupported .p12 file, want a password-protected export")
+	}
+	// RawValues read through an explicit [0] tag keep the tag, so their Bytes
+	// hold the whole wrapped value
+	var authSafe []byte
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return tls.Certificate{}, fmt.Errorf("reading .p12 contents: %w", err)
+	}
+	if err := verifyPKCS12MAC(pfx.MacData, authSafe, password); err != nil {
+		return tls.Certificate{}, err
+	}
+
+	var contents []contentInfo
+	if _, err := asn1.Unmarshal(authSafe, &contents); err != nil {
+		return tls.Certificate{}, fmt.Errorf("reading .p12 contents: %w", err)
+	}
+	var cert tls.Certificate
+	for _, ci := range contents {
+		var safe []byte
+		switch {
+		case ci.ContentType.Equal(oidDataContentType):
+			if _, err := asn1.Unmarshal(ci.Content.Bytes, &safe); err != nil {
+				return tls.Certificate{}, fmt.Errorf("reading .p12 contents: %w", err)
+			}
+		case ci.ContentType.Equal(oidEncryptedDataContentType):
+			var ed encryptedData
+			if _, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+				return tls.Certificate{}, fmt.Errorf("reading .p12 contents: %w", err)
+			}
+			info := ed.EncryptedContentInfo
+			if safe, err = pkcs12Decrypt(info.ContentEncryptionAlgorithm, info.EncryptedContent, password); err != nil {
+				return tls.Certificate{}, err
+			}
+		default:
+			continue
+		}
+
+		var bags []safeBag
+		if _, err := asn1.Unmarshal(safe, &bags); err != nil {
+			return tls.Certificate{}, fmt.Errorf("reading .p12 contents: %w", err)
+		}
+		for _, bag := range bags {
+			if err := addPKCS12Bag(&cert, bag, password); err != nil {
+				return tls.Certificate{}, err
+			}
+		}
+	}
+	if len(cert.Certificate) == 0 || cert.PrivateKey == nil {
+		return tls.Certificate{}, errors.New(".p12 file must hold both the certificate and its private key")
+	}
+	return cert, leafFirst(&cert)
+}
+
+// addPKCS12Bag adds the certificate or key in bag to cert, skipping other
+// bag types
+func addPKCS12Bag(cert *tls.Certificate, bag safeBag, password string) error {
+	switch {
+	case bag.ID.Equal(oidCertBag):
+		var cb certBag
+		if _, err := asn1.Unmarshal(bag.Value.Bytes, &cb); err != nil {
+			return fmt.Errorf("reading .p12 certificate: %w", err)
+		}
+		if cb.ID.Equal(oidX509Certificate) {
+			cert.Certificate = append(cert.Certificate, cb.Data)
+		}
+		return nil
+	case bag.ID.Equal(oidKeyBag), bag.ID.Equal(oidPKCS8ShroudedKeyBag):
+		keyDER := bag.Value.Bytes
+		if bag.ID.Equal(oidPKCS8ShroudedKeyBag) {
+			var info encryptedPrivateKeyInfo
+			if _, err := asn1.Unmarshal(bag.Value.Bytes, &info); err != nil {
+				return fmt.Errorf("reading .p12 key: %w", err)
+			}
+			var err error
+			if keyDER, err = pkcs12Decrypt(info.Algorithm, info.EncryptedData, password); err != nil {
+				return err
+			}
+		}
+		key, err := x509.ParsePKCS8PrivateKey(keyDER)
+		if err != nil {
+			return fmt.Errorf("reading .p12 key: %w", err)
+		}
+		cert.PrivateKey = key
+		return nil
+	}
+	return nil
+}
+
+// leafFirst moves the certificate matching cert's private key to the
+// front of the chain, where TLS expects the leaf
+func leafFirst(cert *tls.Certificate) error {
+	signer, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return errors.New(".p12 key can't sign, want an RSA or ECDSA key")
+	}
+	public := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	for i, certDER := range cert.Certificate {
+		leaf, err := x509.ParseCertificate(certDER)
+		if err != nil {
+			return fmt.Errorf("reading .p12 certificate: %w", err)
+		}
+		if public.Equal(leaf.PublicKey) {
+			cert.Certificate[0], cert.Certificate[i] = cert.Certificate[i], cert.Certificate[0]
+			cert.Leaf = leaf
+			return nil
+		}
+	}
+	return errors.New(".p12 file has no certificate for its private key")
+}
+
+// verifyPKCS12MAC checks the file's integrity MAC, which is keyed by the
+// password
+func verifyPKCS12MAC(mac macData, content []byte, password string) error {
+	var newHash func() hash.Hash
+	switch alg := mac.Mac.Algorithm.Algorithm; {
+	case alg.Equal(oidSHA1):
+		newHash = sha1.New
+	case alg.Equal(oidSHA256):
+		newHash = sha256.New
+	case len(alg) == 0:
+		return errors.New("unsupported .p12 file, want a password-protected export")
+	default:
+		return fmt.Errorf("unsupported .p12 MAC algorithm %v", alg)
+	}
+	// openssl keys the MAC for an empty password with either an empty
+	// string or no string at all, depending on how the file was made
+	passwords := [][]byte{bmpPassword(password)}
+	if password == "" {
+		passwords = append(passwords, nil)
+	}
+	for _, encoded := range passwords {
+		key := pkcs12KDF(newHash, encoded, mac.MacSalt, mac.Iterations, 3, newHash().Size())
+		h := hmac.New(newHash, key)
+		h.Write(content)
+		if hmac.Equal(h.Sum(nil), mac.Mac.Digest) {
+			return nil
+		}
+	}
+	return errPKCS12Password
+}
+
+// pkcs12Decrypt decrypts a bag or content encrypted under alg
+func pkcs12Decrypt(alg pkix.AlgorithmIdentifier, ciphertext []byte, password string) ([]byte, error) {
+	var block cipher.Block
+	var iv []byte
+	switch {
+	case alg.Algorithm.Equal(oidPBEWithSHAAnd3KeyTripleDESCBC), alg.Algorithm.Equal(oidPBEWithSHAAnd40BitRC2CBC):
+		var params pbeParams
+		if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+			return nil, fmt.Errorf("reading .p12 encryption parameters: %w", err)
+		}
+		encoded := bmpPassword(password)
+		iv = pkcs12KDF(sha1.New, encoded, params.Salt, params.Iterations, 2, 8)
+		if alg.Algorithm.Equal(oidPBEWithSHAAnd3KeyTripleDESCBC) {
+			block, _ = des.NewTripleDESCipher(pkcs12KDF(sha1.New, encoded, params.Salt, params.Iterations, 1, 24))
+		} else {
+			block = newRC2Cipher(pkcs12KDF(sha1.New, encoded, params.Salt, params.Iterations, 1, 5), 40)
+		}
+	case alg.Algorithm.Equal(oidPBES2):
+		var err error
+		if block, iv, err = pbes2Cipher(alg, password); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported .p12 encryption %v", alg.Algorithm)
+	}
+
+	size := block.BlockSize()
+	if len(ciphertext) == 0 || len(ciphertext)%size != 0 || len(iv) != size {
+		return nil, errPKCS12Password
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > size || !bytes.Equal(plaintext[len(plaintext)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, errPKCS12Password
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// pbes2Cipher builds the AES cipher and IV for PBES2 with PBKDF2, the
+// scheme openssl 3 exports with by default
+func pbes2Cipher(alg pkix.AlgorithmIdentifier, password string) (cipher.Block, []byte, error) {
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+		return nil, nil, fmt.Errorf("reading .p12 encryption parameters: %w", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, nil, fmt.Errorf("unsupported .p12 key derivation %v", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, nil, fmt.Errorf("reading .p12 encryption parameters: %w", err)
+	}
+	prf := sha1.New
+	switch {
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	case len(kdf.PRF.Algorithm) > 0 && !kdf.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		return nil, nil, fmt.Errorf("unsupported .p12 key derivation PRF %v", kdf.PRF.Algorithm)
+	}
+
+	var keyLen int
+	switch scheme := params.EncryptionScheme.Algorithm; {
+	case scheme.Equal(oidAES128CBC):
+		keyLen = 16
+	case scheme.Equal(oidAES192CBC):
+		keyLen = 24
+	case scheme.Equal(oidAES256CBC):
+		keyLen = 32
+	default:
+		return nil, nil, fmt.Errorf("unsupported .p12 encryption %v", scheme)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, nil, fmt.Errorf("reading .p12 encryption parameters: %w", err)
+	}
+	key, err := pbkdf2.Key(prf, password, kdf.Salt, kdf.Iterations, keyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, err := aes.NewCipher(key)
+	return block, iv, err
+}
+
+// bmpPassword encodes password the way the PKCS#12 key derivation wants
+// it: UTF-16 big-endian with a terminating zero
+func bmpPassword(password string) []byte {
+	var encoded []byte
+	for _, unit := range utf16.Encode([]rune(password)) {
+		encoded = binary.BigEndian.AppendUint16(encoded, unit)
+	}
+	return append(encoded, 0, 0)
+}
+
+// pkcs12KDF derives size bytes of key material for purpose id (1 for keys,
+// 2 for IVs, 3 for MAC keys) as in RFC 7292 appendix B.2
+func pkcs12KDF(newHash func() hash.Hash, password, salt []byte, iterations int, id byte, size int) []byte {
+	h := newHash()
+	v := h.BlockSize()
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		out := make([]byte, v*((len(b)+v-1)/v))
+		for i := range out {
+			out[i] = b[i%len(b)]
+		}
+		return out
+	}
+	diversifier := bytes.Repeat([]byte{id}, v)
+	input := append(fill(salt), fill(password)...)
+
+	var out []byte
+	for {
+		h.Reset()
+		h.Write(diversifier)
+		h.Write(input)
+		a := h.Sum(nil)
+		for i := 1; i < iterations; i++ {
+			h.Reset()
+			h.Write(a)
+			a = h.Sum(a[:0])
+		}
+		out = append(out, a...)
+		if len(out) >= size {
+			return out[:size]
+		}
+		// Each v-byte block of the input becomes block + a + 1
+		b := fill(a)[:v]
+		for j := 0; j < len(input); j += v {
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				sum := int(input[j+k]) + int(b[k]) + carry
+				input[j+k], carry = byte(sum), sum>>8
+			}
+		}
+	}
+}
+
+// berToDER rewrites the indefinite lengths some exporters use as definite
+// ones, which is all encoding/asn1 reads
+func berToDER(ber []byte) ([]byte, error) {
+	der, rest, err := berElement(ber)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("trailing data after ASN.1 structure")
+	}
+	return der, nil
+}
+
+// berElement converts the first element of ber to DER and returns what
+// follows it
+func berElement(ber []byte) (der, rest []byte, err error) {
+	errTruncated := errors.New("truncated ASN.1 structure")
+	tagLen := 1
+	if len(ber) > 0 && ber[0]&0x1f == 0x1f {
+		for tagLen < len(ber) && ber[tagLen]&0x80 != 0 {
+			tagLen++
+		}
+		tagLen++
+	}
+	if len(ber) < tagLen+1 {
+		return nil, nil, errTruncated
+	}
+	tag, constructed := ber[:tagLen], ber[0]&0x20 != 0
+	lengthByte, rest := ber[tagLen], ber[tagLen+1:]
+
+	var content []byte
+	switch {
+	case lengthByte == 0x80:
+		if !constructed {
+			return nil, nil, errors.New("indefinite length on a primitive ASN.1 value")
+		}
+		for {
+			if len(rest) < 2 {
+				return nil, nil, errTruncated
+			}
+			if rest[0] == 0 && rest[1] == 0 {
+				return berTLV(tag, content), rest[2:], nil
+			}
+			var child []byte
+			if child, rest, err = berElement(rest); err != nil {
+				return nil, nil, err
+			}
+			content = append(content, child...)
+		}
+	case lengthByte&0x80 != 0:
+		n := int(lengthByte & 0x7f)
+		if n > 4 || n > len(rest) {
+			return nil, nil, errTruncated
+		}
+		length := 0
+		for _, b := range rest[:n] {
+			length = length<<8 | int(b)
+		}
+		rest = rest[n:]
+		if length > len(rest) {
+			return nil, nil, errTruncated
+		}
+		content, rest = rest[:length], rest[length:]
+	default:
+		if int(lengthByte) > len(rest) {
+			return nil, nil, errTruncated
+		}
+		content, rest = rest[:lengthByte], rest[lengthByte:]
+	}
+
+	if constructed {
+		var children []byte
+		for len(content) > 0 {
+			var child []byte
+			if child, content, err = berElement(content); err != nil {
+				return nil, nil, err
+			}
+			children = append(children, child...)
+		}
+		content = children
+	}
+	return berTLV(tag, content), rest, nil
+}
+
+// berTLV encodes tag and content with a definite length
+func berTLV(tag, content []byte) []byte {
+	out := append([]byte{}, tag...)
+	if n := len(content); n < 0x80 {
+		out = append(out, byte(n))
+	} else {
+		var length []byte
+		for ; n > 0; n >>= 8 {
+			length = append([]byte{byte(n)}, length...)
+		}
+		out = append(append(out, 0x80|byte(len(length))), length...)
+	}
+	return append(out, content...)
+}
+
+// rc2PITable is the permutation from RFC 2268 section 2
+var rc2PITable = [256]byte{
+	0xd9, 0x78, 0xf9, 0xc4, 0x19, 0xdd, 0xb5, 0xed, 0x28, 0xe9, 0xfd, 0x79, 0x4a, 0xa0, 0xd8, 0x9d,
+	0xc6, 0x7e, 0x37, 0x83, 0x2b, 0x76, 0x53, 0x8e, 0x62, 0x4c, 0x64, 0x88, 0x44, 0x8b, 0xfb, 0xa2,
+	0x17, 0x9a, 0x59, 0xf5, 0x87, 0xb3, 0x4f, 0x13, 0x61, 0x45, 0x6d, 0x8d, 0x09, 0x81, 0x7d, 0x32,
+	0xbd, 0x8f, 0x40, 0xeb, 0x86, 0xb7, 0x7b, 0x0b, 0xf0, 0x95, 0x21, 0x22, 0x5c, 0x6b, 0x4e, 0x82,
+	0x54, 0xd6, 0x65, 0x93, 0xce, 0x60, 0xb2, 0x1c, 0x73, 0x56, 0xc0, 0x14, 0xa7, 0x8c, 0xf1, 0xdc,
+	0x12, 0x75, 0xca, 0x1f, 0x3b, 0xbe, 0xe4, 0xd1, 0x42, 0x3d, 0xd4, 0x30, 0xa3, 0x3c, 0xb6, 0x26,
+	0x6f, 0xbf, 0x0e, 0xda, 0x46, 0x69, 0x07, 0x57, 0x27, 0xf2, 0x1d, 0x9b, 0xbc, 0x94, 0x43, 0x03,
+	0xf8, 0x11, 0xc7, 0xf6, 0x90, 0xef, 0x3e, 0xe7, 0x06, 0xc3, 0xd5, 0x2f, 0xc8, 0x66, 0x1e, 0xd7,
+	0x08, 0xe8, 0xea, 0xde, 0x80, 0x52, 0xee, 0xf7, 0x84, 0xaa, 0x72, 0xac, 0x35, 0x4d, 0x6a, 0x2a,
+	0x96, 0x1a, 0xd2, 0x71, 0x5a, 0x15, 0x49, 0x74, 0x4b, 0x9f, 0xd0, 0x5e, 0x04, 0x18, 0xa4, 0xec,
+	0xc2, 0xe0, 0x41, 0x6e, 0x0f, 0x51, 0xcb, 0xcc, 0x24, 0x91, 0xaf, 0x50, 0xa1, 0xf4, 0x70, 0x39,
+	0x99, 0x7c, 0x3a, 0x85, 0x23, 0xb8, 0xb4, 0x7a, 0xfc, 0x02, 0x36, 0x5b, 0x25, 0x55, 0x97, 0x31,
+	0x2d, 0x5d, 0xfa, 0x98, 0xe3, 0x8a, 0x92, 0xae, 0x05, 0xdf, 0x29, 0x10, 0x67, 0x6c, 0xba, 0xc9,
+	0xd3, 0x00, 0xe6, 0xcf, 0xe1, 0x9e, 0xa8, 0x2c, 0x63, 0x16, 0x01, 0x3f, 0x58, 0xe2, 0x89, 0xa9,
+	0x0d, 0x38, 0x34, 0x1b, 0xab, 0x33, 0xff, 0xb0, 0xbb, 0x48, 0x0c, 0x5f, 0xb9, 0xb1, 0xcd, 0x2e,
+	0xc5, 0xf3, 0xdb, 0x47, 0xe5, 0xa5, 0x9c, 0x77, 0x0a, 0xa6, 0x20, 0x68, 0xfe, 0x7f, 0xc1, 0xad,
+}
+
+// rc2Cipher is RC2 (RFC 2268), which older exports encrypt certificates
+// with. It is long broken and only here to read those files
+type rc2Cipher struct {
+	k [64]uint16
+}
+
+// newRC2Cipher expands key with the given effective key length in bits
+func newRC2Cipher(key []byte, effectiveBits int) *rc2Cipher {
+	var l [128]byte
+	copy(l[:], key)
+	for i := len(key); i < 128; i++ {
+		l[i] = rc2PITable[l[i-1]+l[i-len(key)]]
+	}
+	t8 := (effectiveBits + 7) / 8
+	tm := byte(0xff >> (8*t8 - effectiveBits))
+	l[128-t8] = rc2PITable[l[128-t8]&tm]
+	for i := 127 - t8; i >= 0; i-- {
+		l[i] = rc2PITable[l[i+1]^l[i+t8]]
+	}
+	c := &rc2Cipher{}
+	for i := range c.k {
+		c.k[i] = uint16(l[2*i]) | uint16(l[2*i+1])<<8
+	}
+	return c
+}
+
+// rc2Shifts are the rotations of the four words in a mixing round
+var rc2Shifts = [4]int{1, 2, 3, 5}
+
+func (c *rc2Cipher) BlockSize() int { return 8 }
+
+func (c *rc2Cipher) Encrypt(dst, src []byte) {
+	var r [4]uint16
+	for i := range r {
+		r[i] = binary.LittleEndian.Uint16(src[2*i:])
+	}
+	j := 0
+	mix := func() {
+		for i := range r {
+			r[i] += c.k[j] + (r[(i+3)%4] & r[(i+2)%4]) + (^r[(i+3)%4] & r[(i+1)%4])
+			r[i] = bits.RotateLeft16(r[i], rc2Shifts[i])
+			j++
+		}
+	}
+	mash := func() {
+		for i := range r {
+			r[i] += c.k[r[(i+3)%4]&63]
+		}
+	}
+	for round := 0; round < 16; round++ {
+		mix()
+		if round == 4 || round == 10 {
+			mash()
+		}
+	}
+	for i := range r {
+		binary.LittleEndian.PutUint16(dst[2*i:], r[i])
+	}
+}
+
+func (c *rc2Cipher) Decrypt(dst, src []byte) {
+	var r [4]uint16
+	for i := range r {
+		r[i] = binary.LittleEndian.Uint16(src[2*i:])
+	}
+	j := 63
+	unmix := func() {
+		for i := 3; i >= 0; i-- {
+			r[i] = bits.RotateLeft16(r[i], -rc2Shifts[i])
+			r[i] -= c.k[j] + (r[(i+3)%4] & r[(i+2)%4]) + (^r[(i+3)%4] & r[(i+1)%4])
+			j--
+		}
+	}
+	unmash := func() {
+		for i := 3; i >= 0; i-- {
+			r[i] -= c.k[r[(i+3)%4]&63]
+		}
+	}
+	for round := 15; round >= 0; round-- {
+		unmix()
+		if round == 5 || round == 11 {
+			unmash()
+		}
+	}
+	for i := range r {
+		binary.LittleEndian.PutUint16(dst[2*i:], r[i])
+	}
+}
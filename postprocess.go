@@ -0,0 +1,95 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// modelArtifactPattern matches special tokens models sometimes leak, like <n> or </s>
+	modelArtifactPattern = regexp.MustCompile(`</?(n|s|pad|unk|mask)>`)
+	// whitespacePattern matches runs of whitespace
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// sentenceEnders are the characters that end a complete sentence, including
+// a closing quote after the punctuation
+const sentenceEnders = ".!?\"'”’"
+
+// closingQuotes may follow a sentence's final punctuation
+const closingQuotes = "\"'”’)"
+
+// abbreviations end in a period without ending the sentence
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "st": true,
+	"jr": true, "sr": true, "gov": true, "sen": true, "rep": true, "gen": true,
+	"col": true, "lt": true, "sgt": true, "inc": true, "corp": true, "co": true,
+	"ltd": true, "vs": true, "no": true, "jan": true, "feb": true, "aug": true,
+	"sept": true, "oct": true, "nov": true, "dec": true,
+}
+
+// postProcessSummary cleans up common model artifacts: leaked special
+// tokens, stray whitespace, a repeated leading word ("The The ...") and a
+// trailing sentence cut off mid-way
+func postProcessSummary(s string) string {
+	s = modelArtifactPattern.ReplaceAllString(s, " ")
+	s = strings.TrimSpace(whitespacePattern.ReplaceAllString(s, " "))
+
+	words := strings.Split(s, " ")
+	for len(words) > 1 && strings.EqualFold(words[0], words[1]) {
+		words = words[1:]
+	}
+	s = strings.Join(words, " ")
+
+	// Drop an unfinished final sentence, as long as a complete one remains
+	if last, _ := utf8.DecodeLastRuneInString(s); s != "" && !strings.ContainsRune(sentenceEnders, last) {
+		if cut := lastSentenceEnd(s); cut > 0 {
+			s = s[:cut]
+		}
+	}
+	return strings.TrimSpace(s)
+}
+
+// lastSentenceEnd returns the index just past the last complete sentence in
+// s, including any closing quote, or 0 when there is none. Periods after
+// abbreviations and initials, as in "Dr." or "U.S.", don't end a sentence
+func lastSentenceEnd(s string) int {
+	for i := len(s) - 1; i > 0; i-- {
+		if s[i] != '.' && s[i] != '!' && s[i] != '?' {
+			continue
+		}
+		end := i + 1
+		for end < len(s) {
+			r, size := utf8.DecodeRuneInString(s[end:])
+			if !strings.ContainsRune(closingQuotes, r) {
+				break
+			}
+			end += size
+		}
+		if end == len(s) || s[end] != ' ' {
+			continue
+		}
+		if s[i] == '.' && isAbbreviation(s[:i]) {
+			continue
+		}
+		return end
+	}
+	return 0
+}
+
+// isAbbreviation reports whether the word at the end of s is shortened, so
+// that a period after it doesn't end the sentence
+func isAbbreviation(s string) bool {
+	word := strings.TrimLeft(s[strings.LastIndexByte(s, ' ')+1:], "(\"'“‘")
+	if strings.Contains(word, ".") {
+		// An initialism like "U.S" or "e.g"
+		return true
+	}
+	if first, size := utf8.DecodeRuneInString(word); size == len(word) && unicode.IsUpper(first) {
+		// An initial, as in "John F. Kennedy"
+		return true
+	}
+	return abbreviations[strings.ToLower(word)]
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestPostProcessSummary(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"artifacts and whitespace", "The <n> council  voted.</s>", "The council voted."},
+		{"repeated leading word", "The The council voted.", "The council voted."},
+		{"cut-off final sentence", "The council voted. It will meet again on", "The council voted."},
+		{"no complete sentence to keep", "The council voted on", "The council voted on"},
+		{"ends with a straight quote", `He said "the vote is final."`, `He said "the vote is final."`},
+		{"ends with a curly quote", "He said “the vote is final.”", "He said “the vote is final.”"},
+		{"ends with a curly single quote", "She called it ‘a win.’", "She called it ‘a win.’"},
+		{"cut after a curly quote", "He said “it is final.” The mayor then", "He said “it is final.”"},
+		{"initialism", "The U.S. economy grew 3.5% as hiring", "The U.S. economy grew 3.5% as hiring"},
+		{"initialism before a real end", "Hiring rose. The U.S. economy grew as", "Hiring rose."},
+		{"title abbreviation", "Talks led by Dr. Smith and Sen. Jones stalled as", "Talks led by Dr. Smith and Sen. Jones stalled as"},
+		{"initial in a name", "John F. Kennedy airport closed as", "John F. Kennedy airport closed as"},
+		{"question and exclamation", "Is it over? Yes! But the", "Is it over? Yes!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := postProcessSummary(tt.in); got != tt.want {
+				t.Errorf("postProcessSummary(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+	_ "time/tzdata" // so TIMEZONE works on hosts without zoneinfo
+)
+
+// PendingPost is a summarized story held back until the posting window opens,
+// or one Slack's per-run cap held back after the other destinations got it
+type PendingPost struct {
+	Story     Story     `json:"story"`
+	Summary   string    `json:"summary"`
+	SlackOnly bool      `json:"slack_only,omitempty"`
+	HeldAt    time.Time `json:"held_at,omitempty"` // when the story was first held
+}
+
+// postWindow is the daily time-of-day range in which posting is allowed
+type postWindow struct {
+	start, end time.Duration // offsets from local midnight
+	loc        *time.Location
+}
+
+// botLocation returns the configured TIMEZONE, defaulting to local time
+func botLocation() (*time.Location, error) {
+	if name := os.Getenv("TIMEZONE"); name != "" {
+		return time.LoadLocation(name)
+	}
+	return time.Local, nil
+}
+
+// postWindowFromEnv reads POST_WINDOW_START and POST_WINDOW_END (HH:MM,
+// 24-hour), reporting whether a window is configured
+func postWindowFromEnv() (postWindow, bool, error) {
+	startValue, endValue := os.Getenv("POST_WINDOW_START"), os.Getenv("POST_WINDOW_END")
+	if startValue == "" && endValue == "" {
+		return postWindow{}, false, nil
+	}
+	var w postWindow
+	var err error
+	if w.start, err = parseClock(startValue); err != nil {
+		return w, false, fmt.Errorf("POST_WINDOW_START: %w", err)
+	}
+	if w.end, err = parseClock(endValue); err != nil {
+		return w, false, fmt.Errorf("POST_WINDOW_END: %w", err)
+	}
+	if w.loc, err = botLocation(); err != nil {
+		return w, false, fmt.Errorf("TIMEZONE: %w", err)
+	}
+	return w, true, nil
+}
+
+// parseClock parses an HH:MM time of day into an offset from midnight
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// sinceMidnight returns how far into its day t is, in the window's timezone
+func (w postWindow) sinceMidnight(t time.Time) time.Duration {
+	t = t.In(w.loc)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, w.loc)
+	return t.Sub(midnight)
+}
+
+// contains reports whether t falls inside the window. Windows whose end is
+// before their start wrap past midnight
+func (w postWindow) contains(t time.Time) bool {
+	offset := w.sinceMidnight(t)
+	if w.start <= w.end {
+		return offset >= w.start && offset < w.end
+	}
+	return offset >= w.start || offset < w.end
+}
+
+// nextOpen returns the next time at or after t that the window opens
+func (w postWindow) nextOpen(t time.Time) time.Time {
+	local := t.In(w.loc)
+	open := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, w.loc).Add(w.start)
+	if open.Before(t) {
+		open = open.AddDate(0, 0, 1)
+	}
+	return open
+}
+
+// maxPendingHold reads POST_WINDOW_MAX_HOLD_HOURS (default 24), how long a
+// story may be held before it's too stale to post. 0 holds stories forever
+func maxPendingHold() time.Duration {
+	return time.Duration(envInt("POST_WINDOW_MAX_HOLD_HOURS", 24)) * time.Hour
+}
+
+// dropStalePending drops held posts first held longer than maxHold ago, so
+// a window that never opens doesn't post days-old news. Posts held before
+// hold times were recorded are kept
+func dropStalePending(pending []PendingPost, maxHold time.Duration, now time.Time) []PendingPost {
+	if maxHold <= 0 {
+		return pending
+	}
+	var kept []PendingPost
+	for _, p := range pending {
+		if !p.HeldAt.IsZero() && now.Sub(p.HeldAt) > maxHold {
+			log.Printf("Dropping story held since %s, past POST_WINDOW_MAX_HOLD_HOURS: %s", p.HeldAt.Format(time.RFC3339), p.Story.Title)
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return kept
+}
+
+// heldSince returns when the story at link was first held, or now if it
+// wasn't held before
+func heldSince(holding map[string]PendingPost, link string, now time.Time) time.Time {
+	if p, ok := holding[link]; ok && !p.HeldAt.IsZero() {
+		return p.HeldAt
+	}
+	return now
+}
+
+// withoutPending drops stories already held in pending so they're not summarized twice
+func withoutPending(stories []Story, pending []PendingPost) []Story {
+	held := make(map[string]bool)
+	for _, p := range pending {
+		held[p.Story.Link] = true
+	}
+	var kept []Story
+	for _, story := range stories {
+		if !held[story.Link] {
+			kept = append(kept, story)
+		}
+	}
+	return kept
+}
+
+// insertPending places held-back posts after any pinned stories at the top
+func insertPending(stories []Story, summaries []string, pending []PendingPost) ([]Story, []string) {
+	if len(pending) == 0 {
+		return stories, summaries
+	}
+	pinned := 0
+	for pinned < len(stories) && stories[pinned].Pinned {
+		pinned++
+	}
+	var mergedStories []Story
+	var mergedSummaries []string
+	mergedStories = append(mergedStories, stories[:pinned]...)
+	mergedSummaries = append(mergedSummaries, summaries[:pinned]...)
+	for _, p := range pending {
+		mergedStories = append(mergedStories, p.Story)
+		mergedSummaries = append(mergedSummaries, p.Summary)
+	}
+	mergedStories = append(mergedStories, stories[pinned:]...)
+	mergedSummaries = append(mergedSummaries, summaries[pinned:]...)
+	return mergedStories, mergedSummaries
+}
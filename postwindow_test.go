@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDropStalePending(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	fresh := PendingPost{Story: Story{Title: "fresh", Link: "https://example.com/a"}, HeldAt: now.Add(-2 * time.Hour)}
+	stale := PendingPost{Story: Story{Title: "stale", Link: "https://example.com/b"}, HeldAt: now.Add(-30 * time.Hour)}
+	legacy := PendingPost{Story: Story{Title: "held before hold times", Link: "https://example.com/c"}}
+
+	got := dropStalePending([]PendingPost{fresh, stale, legacy}, 24*time.Hour, now)
+	if len(got) != 2 || got[0].Story.Title != "fresh" || got[1].Story.Title != "held before hold times" {
+		t.Errorf("dropStalePending() kept %v, want fresh and the legacy post", got)
+	}
+	if got := dropStalePending([]PendingPost{stale}, 0, now); len(got) != 1 {
+		t.Error("dropStalePending() with no limit dropped a post")
+	}
+
+	holding := map[string]PendingPost{fresh.Story.Link: fresh}
+	if got := heldSince(holding, fresh.Story.Link, now); !got.Equal(fresh.HeldAt) {
+		t.Errorf("heldSince() = %v, want the first hold time %v", got, fresh.HeldAt)
+	}
+	if got := heldSince(holding, "https://example.com/new", now); !got.Equal(now) {
+		t.Errorf("heldSince() for a new story = %v, want now", got)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// domainBoosts maps domains to the factor their stories' scores are
+// multiplied by for ranking. A domain also covers its subdomains
+type domainBoosts map[string]float64
+
+var (
+	priorityDomains     domainBoosts
+	priorityDomainsOnce sync.Once
+)
+
+// priorityDomainsFromEnv reads PRIORITY_DOMAINS, comma-separated
+// domain=factor pairs such as "apnews.com=2,reuters.com=2,tabloid.example=0.5".
+// Invalid entries are logged and ignored
+func priorityDomainsFromEnv() domainBoosts {
+	priorityDomainsOnce.Do(func() {
+		priorityDomains = parseDomainBoosts(os.Getenv("PRIORITY_DOMAINS"))
+	})
+	return priorityDomains
+}
+
+// parseDomainBoosts parses a PRIORITY_DOMAINS value
+func parseDomainBoosts(value string) domainBoosts {
+	boosts := make(domainBoosts)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		domain, factorValue, _ := strings.Cut(entry, "=")
+		factor, err := strconv.ParseFloat(strings.TrimSpace(factorValue), 64)
+		if err != nil || factor <= 0 {
+			log.Printf("Invalid PRIORITY_DOMAINS entry %q, want domain=factor with a factor above 0", entry)
+			continue
+		}
+		boosts[strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "www."))] = factor
+	}
+	return boosts
+}
+
+// of returns the boost for story's article domain, 1 when none is configured
+func (b domainBoosts) of(story Story) float64 {
+	for domain := strings.ToLower(storyDomain(articleLink(story))); domain != ""; {
+		if factor, ok := b[domain]; ok {
+			return factor
+		}
+		_, domain, _ = strings.Cut(domain, ".")
+	}
+	return 1
+}
+
+// rankValues returns the value each story ranks by, highest first: its
+// score times its domain's boost. When no story has a score, as in RSS
+// feeds, the boost divides its listing position instead, so a story boosted
+// ×2 ranks as if listed twice as high
+func (b domainBoosts) rankValues(stories []Story) []float64 {
+	scored := false
+	for _, story := range stories {
+		if story.Score != 0 {
+			scored = true
+			break
+		}
+	}
+	values := make([]float64, len(stories))
+	for i, story := range stories {
+		if scored {
+			values[i] = float64(story.Score) * b.of(story)
+		} else {
+			values[i] = b.of(story) / float64(i+1)
+		}
+	}
+	return values
+}
+
+// outranks reports whether story x, ranking by xValue, ranks above y: by
+// rank value, then by score alone
+func outranks(x Story, xValue float64, y Story, yValue float64) bool {
+	if xValue != yValue {
+		return xValue > yValue
+	}
+	return x.Score > y.Score
+}
+
+// priorityNote describes the boost applied to story, or returns "" when
+// there is none
+func priorityNote(story Story, boosts domainBoosts) string {
+	boost := boosts.of(story)
+	if boost == 1 {
+		return ""
+	}
+	if story.Score == 0 {
+		return fmt.Sprintf("PRIORITY_DOMAINS boost ×%g: unscored, ranks as its listing position divided by %g", boost, boost)
+	}
+	return fmt.Sprintf("PRIORITY_DOMAINS boost ×%g: score %d ranks as %.0f", boost, story.Score, float64(story.Score)*boost)
+}
+
+// orderByPriority sorts stories by rank value, highest first, keeping the
+// listing's order among ties. Without boosts the listing's order stands
+func orderByPriority(stories []Story, boosts domainBoosts) []Story {
+	if len(boosts) == 0 {
+		return stories
+	}
+	values := boosts.rankValues(stories)
+	order := make([]int, len(stories))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return outranks(stories[order[i]], values[order[i]], stories[order[j]], values[order[j]])
+	})
+	ordered := make([]Story, len(stories))
+	for i, index := range order {
+		ordered[i] = stories[index]
+	}
+	return ordered
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOrderByPriority(t *testing.T) {
+	boosts := parseDomainBoosts("apnews.com=2, www.reuters.com=2, tabloid.example=0.5, bad.example=x")
+	tabloid := Story{Title: "tabloid", Link: "https://www.tabloid.example/a", Score: 300}
+	wire := Story{Title: "wire", Link: "https://apnews.com/article/b", Score: 200}
+	subdomain := Story{Title: "subdomain", Link: "https://www.reddit.com/r/news/comments/c/", ArticleURL: "https://uk.reuters.com/c", Score: 100}
+	unboostedTie := Story{Title: "unboosted tie", Link: "https://example.com/d", Score: 200}
+	plain := Story{Title: "plain", Link: "https://example.com/e", Score: 150}
+	plainLater := Story{Title: "plain, listed later", Link: "https://example.org/f", Score: 150}
+
+	got := orderByPriority([]Story{plain, tabloid, unboostedTie, subdomain, plainLater, wire}, boosts)
+	var titles []string
+	for _, story := range got {
+		titles = append(titles, story.Title)
+	}
+	// wire ranks as 400; unboosted tie and subdomain as 200, split by raw
+	// score; tabloid, plain and plain listed later as 150, where tabloid's
+	// raw score puts it first and listing order settles the rest
+	want := []string{"wire", "unboosted tie", "subdomain", "tabloid", "plain", "plain, listed later"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("orderByPriority() = %v, want %v", titles, want)
+	}
+	if _, ok := boosts["bad.example"]; ok {
+		t.Error("invalid PRIORITY_DOMAINS entry was kept")
+	}
+}
+
+func TestDedupeTitlesPrefersBoostedDomain(t *testing.T) {
+	boosts := parseDomainBoosts("apnews.com=2")
+	tabloid := Story{Title: "Storm closes schools across the state", Link: "https://tabloid.example/a", Score: 300}
+	wire := Story{Title: "Storm closes schools across the state!", Link: "https://apnews.com/b", Score: 200}
+
+	got := dedupeTitles([]Story{tabloid, wire}, defaultTitleDedupThreshold, boosts)
+	if len(got) != 1 || got[0].Link != wire.Link {
+		t.Errorf("dedupeTitles() kept %v, want only the wire story", got)
+	}
+	if got := dedupeTitles([]Story{tabloid, wire}, defaultTitleDedupThreshold, nil); got[0].Link != tabloid.Link {
+		t.Errorf("dedupeTitles() without boosts kept %s, want the higher scoring story", got[0].Link)
+	}
+}
+
+func TestOrderByPriorityUnscored(t *testing.T) {
+	boosts := parseDomainBoosts("apnews.com=2")
+	first := Story{Title: "first", Link: "https://example.com/a"}
+	second := Story{Title: "second", Link: "https://example.com/b"}
+	wire := Story{Title: "wire", Link: "https://apnews.com/c"}
+	fourth := Story{Title: "fourth", Link: "https://example.com/d"}
+
+	got := orderByPriority([]Story{first, second, wire, fourth}, boosts)
+	var titles []string
+	for _, story := range got {
+		titles = append(titles, story.Title)
+	}
+	// Listed third and boosted ×2, wire ranks as position 1.5
+	want := []string{"first", "wire", "second", "fourth"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("orderByPriority() = %v, want %v", titles, want)
+	}
+	if note := priorityNote(wire, boosts); !strings.Contains(note, "listing position") {
+		t.Errorf("priorityNote() = %q, want the unscored note", note)
+	}
+
+	tabloid := Story{Title: "Storm closes schools across the state", Link: "https://tabloid.example/a"}
+	wireDuplicate := Story{Title: "Storm closes schools across the state!", Link: "https://apnews.com/b"}
+	// Listed second, it needs more than ×2 to outrank the first
+	if got := dedupeTitles([]Story{tabloid, wireDuplicate}, defaultTitleDedupThreshold, boosts); len(got) != 1 || got[0].Link != tabloid.Link {
+		t.Errorf("dedupeTitles() with a ×2 boost kept %v, want only the earlier story", got)
+	}
+	if got := dedupeTitles([]Story{tabloid, wireDuplicate}, defaultTitleDedupThreshold, parseDomainBoosts("apnews.com=3")); len(got) != 1 || got[0].Link != wireDuplicate.Link {
+		t.Errorf("dedupeTitles() kept %v, want only the wire story", got)
+	}
+}
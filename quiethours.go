@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// quietDestinations are the destinations that can have quiet hours, in the
+// order their roundups are posted
+var quietDestinations = []string{"slack", "zulip", "discord"}
+
+// QueuedPost is a story held for one destination during its quiet hours
+type QueuedPost struct {
+	Story    Story     `json:"story"`
+	Summary  string    `json:"summary"`
+	QueuedAt time.Time `json:"queued_at"`
+}
+
+// quietHours maps destinations to the daily range in which they aren't posted to
+type quietHours map[string]postWindow
+
+// quietHoursFromEnv reads SLACK_QUIET_HOURS, ZULIP_QUIET_HOURS and
+// DISCORD_QUIET_HOURS ("22:00-08:00", in TIMEZONE)
+func quietHoursFromEnv() (quietHours, error) {
+	quiet := make(quietHours)
+	for _, destination := range quietDestinations {
+		key := strings.ToUpper(destination) + "_QUIET_HOURS"
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		startValue, endValue, ok := strings.Cut(value, "-")
+		if !ok {
+			return nil, fmt.Errorf("%s: invalid range %q, want HH:MM-HH:MM", key, value)
+		}
+		var w postWindow
+		var err error
+		if w.start, err = parseClock(strings.TrimSpace(startValue)); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if w.end, err = parseClock(strings.TrimSpace(endValue)); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if w.loc, err = botLocation(); err != nil {
+			return nil, fmt.Errorf("TIMEZONE: %w", err)
+		}
+		quiet[destination] = w
+	}
+	return quiet, nil
+}
+
+// active reports whether destination is in its quiet hours at t
+func (q quietHours) active(destination string, t time.Time) bool {
+	w, ok := q[destination]
+	return ok && w.contains(t)
+}
+
+// queuePost holds a story for destination until its quiet hours end. A
+// story already in the queue isn't added again
+func queuePost(state *State, destination string, story Story, summary string) {
+	for _, p := range state.Queued[destination] {
+		if p.Story.Link == story.Link {
+			return
+		}
+	}
+	if state.Queued == nil {
+		state.Queued = make(map[string][]QueuedPost)
+	}
+	state.Queued[destination] = append(state.Queued[destination], QueuedPost{
+		Story:    story,
+		Summary:  summary,
+		QueuedAt: time.Now(),
+	})
+}
+
+// dueQueuedPosts drops queued posts older than maxAge and ones the store
+// shows were posted again after they were queued
+func dueQueuedPosts(queued []QueuedPost, maxAge time.Duration, store Store, now time.Time) []QueuedPost {
+	var due []QueuedPost
+	for _, p := range queued {
+		if now.Sub(p.QueuedAt) > maxAge {
+			log.Printf("Dropping stale queued story: %s", p.Story.Title)
+			continue
+		}
+		if store != nil {
+			postedAt, ok, err := store.LastPostedAt(p.Story.Link)
+			if err != nil {
+				log.Printf("Error checking store for queued story %s: %v", p.Story.Link, err)
+			} else if ok && postedAt.After(p.QueuedAt) {
+				continue
+			}
+		}
+		due = append(due, p)
+	}
+	return due
+}
+
+// flushQueues posts each queue whose destination is out of its quiet hours
+// as a single roundup through send. Queues of destinations that are no
+// longer configured are dropped; a queue whose roundup fails is kept for
+// the next run
+func flushQueues(state *State, quiet quietHours, store Store, send map[string]func([]QueuedPost) error) {
+	now := time.Now()
+	maxAge := time.Duration(envInt("QUIET_HOURS_MAX_AGE_HOURS", 24)) * time.Hour
+	for _, destination := range quietDestinations {
+		queued, ok := state.Queued[destination]
+		if !ok || quiet.active(destination, now) {
+			continue
+		}
+		post, enabled := send[destination]
+		if !enabled {
+			delete(state.Queued, destination)
+			continue
+		}
+		due := dueQueuedPosts(queued, maxAge, store, now)
+		if len(due) > 0 {
+			if err := post(due); err != nil {
+				log.Printf("Error posting overnight roundup to %s: %v", destination, err)
+				state.Queued[destination] = due
+				continue
+			}
+			log.Printf("Posted overnight roundup of %d stories to %s", len(due), destination)
+		}
+		delete(state.Queued, destination)
+	}
+}
+
+// formatSlackRoundup batches queued posts into one Slack message
+func formatSlackRoundup(posts []QueuedPost) string {
+	messages := []string{"🌙 *Overnight roundup*"}
+	for _, p := range posts {
+		messages = append(messages, formatSlackMessage(p.Story, p.Summary))
+	}
+	return strings.Join(messages, "\n\n")
+}
+
+// formatZulipRoundup batches queued posts into one Zulip message
+func formatZulipRoundup(posts []QueuedPost) string {
+	messages := []string{"🌙 **Overnight roundup**"}
+	for _, p := range posts {
+		messages = append(messages, formatZulipMessage(p.Story, p.Summary))
+	}
+	return strings.Join(messages, "\n\n")
+}
+
+// postDiscordRoundup sends queued posts as embeds under a roundup heading,
+// split across messages only where Discord's per-message embed limit requires
+func postDiscordRoundup(webhookURL string, posts []QueuedPost) error {
+	var embeds []DiscordEmbed
+	for _, p := range posts {
+		embeds = append(embeds, buildDiscordEmbed(p.Story, p.Summary))
+	}
+	content := "🌙 **Overnight roundup**"
+	for len(embeds) > 0 {
+		n := min(len(embeds), discordEmbedsPerMessage)
+		if err := postDiscordMessage(webhookURL, content, embeds[:n]...); err != nil {
+			return err
+		}
+		embeds, content = embeds[n:], ""
+	}
+	return nil
+}
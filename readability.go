@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// defaultTitlePassthroughEase is the Flesch reading-ease score above which a
+// title is considered clear enough to stand in for a summary
+const defaultTitlePassthroughEase = 70.0
+
+// computeReadingLevel returns the Flesch reading-ease score of title. Higher
+// scores are easier to read; 70+ is roughly middle-school level
+func computeReadingLevel(title string) float64 {
+	words := strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	if len(words) == 0 {
+		return 0
+	}
+
+	sentences := strings.Count(title, ".") + strings.Count(title, "!") + strings.Count(title, "?")
+	if sentences == 0 {
+		sentences = 1
+	}
+
+	syllables := 0
+	for _, word := range words {
+		syllables += countSyllables(word)
+	}
+
+	wordsPerSentence := float64(len(words)) / float64(sentences)
+	syllablesPerWord := float64(syllables) / float64(len(words))
+	return 206.835 - 1.015*wordsPerSentence - 84.6*syllablesPerWord
+}
+
+// countSyllables estimates the syllables in word by counting vowel groups
+func countSyllables(word string) int {
+	word = strings.ToLower(word)
+	count := 0
+	prevVowel := false
+	for _, r := range word {
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !prevVowel {
+			count++
+		}
+		prevVowel = vowel
+	}
+	// A trailing silent "e" usually doesn't add a syllable ("make", "state")
+	if strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") && count > 1 {
+		count--
+	}
+	if count == 0 {
+		count = 1
+	}
+	return count
+}
+
+// titlePassthroughEase returns the reading-ease threshold for skipping
+// summarization, or false when TITLE_PASSTHROUGH_EASE=off
+func titlePassthroughEase() (float64, bool) {
+	value := os.Getenv("TITLE_PASSTHROUGH_EASE")
+	switch value {
+	case "":
+		return defaultTitlePassthroughEase, true
+	case "off":
+		return 0, false
+	}
+	ease, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultTitlePassthroughEase, true
+	}
+	return ease, true
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	// defaultRedditBaseURL is the root of Reddit's JSON listing and RSS
+	// endpoints
+	defaultRedditBaseURL = "https://www.reddit.com"
+	// redditUserAgent identifies the bot, as Reddit's API rules require
+	redditUserAgent = "reddit-news-bot/1.0"
+	// searchCandidateLimit is how many search results are considered per run
+	searchCandidateLimit = 25
+)
+
+// redditListing is the subset of a Reddit JSON listing the bot uses
+type redditListing struct {
+	Data struct {
+		Children []struct {
+			Data struct {
+				Title      string  `json:"title"`
+				URL        string  `json:"url"`
+				Author     string  `json:"author"`
+				Score      int     `json:"score"`
+				CreatedUTC float64 `json:"created_utc"`
+				PostHint   string  `json:"post_hint"`
+				IsGallery  bool    `json:"is_gallery"`
+			} `json:"data"`
+		} `json:"children"`
+		// After is the cursor for the next page, empty on the last one
+		After string `json:"after"`
+	} `json:"data"`
+}
+
+// redditBaseURL returns the root of Reddit's endpoints, REDDIT_BASE_URL when
+// set (e.g. a mirror, or a stand-in server in tests)
+func redditBaseURL() string {
+	if base := os.Getenv("REDDIT_BASE_URL"); base != "" {
+		return strings.TrimSuffix(base, "/")
+	}
+	return defaultRedditBaseURL
+}
+
+// fetchStoriesByQuery searches Reddit for posts from the last day matching
+// query, across all of Reddit or within subreddit when one is given
+func fetchStoriesByQuery(query, subreddit, sort string, limit int) ([]Story, error) {
+	return fetchRedditListing(redditSearchURL(query, subreddit, sort, limit))
+}
+
+// redditSearchURL builds a search.json URL for the last day's posts
+func redditSearchURL(query, subreddit, sort string, limit int) string {
+	params := url.Values{
+		"q":     {query},
+		"sort":  {sort},
+		"t":     {"day"},
+		"limit": {strconv.Itoa(limit)},
+	}
+	endpoint := redditBaseURL() + "/search.json"
+	if subreddit != "" {
+		endpoint = redditBaseURL() + "/r/" + url.PathEscape(subreddit) + "/search.json"
+		params.Set("restrict_sr", "1")
+	}
+	return endpoint + "?" + params.Encode()
+}
+
+// fetchRedditListing downloads a Reddit JSON listing and converts its posts to stories
+func fetchRedditListing(listingURL string) ([]Story, error) {
+	stories, _, err := fetchRedditListingPage(listingURL)
+	return stories, err
+}
+
+// fetchRedditListingPage downloads one page of a Reddit JSON listing,
+// returning its stories and the after= cursor for the next page
+func fetchRedditListingPage(listingURL string) ([]Story, string, error) {
+	data, err := fetchRaw(listingURL, "application/json")
+	if err != nil {
+		return nil, "", err
+	}
+	var listing redditListing
+	if err := json.Unmarshal(data, &listing); err != nil {
+		return nil, "", err
+	}
+	return listingStories(listing), listing.Data.After, nil
+}
+
+// parseRedditListing converts the posts in a Reddit JSON listing to stories
+func parseRedditListing(data []byte) ([]Story, error) {
+	var listing redditListing
+	if err := json.Unmarshal(data, &listing); err != nil {
+		return nil, err
+	}
+	return listingStories(listing), nil
+}
+
+// listingStories converts a decoded Reddit listing's posts to stories
+func listingStories(listing redditListing) []Story {
+
+	var stories []Story
+	for _, child := range listing.Data.Children {
+		post := child.Data
+		stories = append(stories, Story{
+			Title:     post.Title,
+			Link:      post.URL,
+			Author:    "/u/" + post.Author,
+			Score:     post.Score,
+			Published: time.Unix(int64(post.CreatedUTC), 0),
+			ImagePost: post.PostHint == "image" || post.IsGallery,
+		})
+	}
+	return stories
+}
+
+// redditOutboundLink returns the article a Reddit RSS entry links out to,
+// which Reddit puts in the entry's content as a "[link]" anchor. Entries
+// that aren't Reddit posts, and self posts, whose "[link]" is the post
+// itself, return ""
+func redditOutboundLink(item *gofeed.Item) string {
+	if domain := storyDomain(item.Link); domain != "reddit.com" && domain != "old.reddit.com" {
+		return ""
+	}
+	content := item.Content
+	if content == "" {
+		content = item.Description
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return ""
+	}
+	var link string
+	doc.Find("a[href]").EachWithBreak(func(_ int, a *goquery.Selection) bool {
+		if strings.TrimSpace(a.Text()) != "[link]" {
+			return true
+		}
+		if href, _ := a.Attr("href"); !sameURL(href, item.Link) {
+			link = href
+		}
+		return false
+	})
+	return link
+}
+
+// redditSearchFromEnv returns the search settings when REDDIT_SEARCH_QUERY is set
+func redditSearchFromEnv() (query, subreddit, sort string, ok bool) {
+	query = os.Getenv("REDDIT_SEARCH_QUERY")
+	subreddit = os.Getenv("REDDIT_SEARCH_SUBREDDIT")
+	sort = os.Getenv("REDDIT_SEARCH_SORT")
+	if sort == "" {
+		sort = "relevance"
+	}
+	return query, subreddit, sort, query != ""
+}
+
+// fetchStoriesMultiWindow fetches a subreddit's top stories for each time
+// window (e.g. "hour", "day", "week"), keyed by window
+func fetchStoriesMultiWindow(subreddit string, windows []string) (map[string][]Story, error) {
+	results := make(map[string][]Story)
+	for _, window := range windows {
+		feed := fmt.Sprintf("%s/r/%s/top/.rss?t=%s", redditBaseURL(), url.PathEscape(subreddit), url.QueryEscape(window))
+		stories, err := fetchRSSFeed(feed)
+		if err != nil {
+			return nil, fmt.Errorf("fetching %s window: %w", window, err)
+		}
+		results[window] = stories
+	}
+	return results, nil
+}
+
+// trendingLinks returns the links that appear in every window's results
+func trendingLinks(byWindow map[string][]Story) map[string]bool {
+	counts := make(map[string]int)
+	for _, stories := range byWindow {
+		seen := make(map[string]bool)
+		for _, story := range stories {
+			if !seen[story.Link] {
+				seen[story.Link] = true
+				counts[story.Link]++
+			}
+		}
+	}
+	trending := make(map[string]bool)
+	for link, count := range counts {
+		if count == len(byWindow) {
+			trending[link] = true
+		}
+	}
+	return trending
+}
+
+// markTrending flags stories that stayed in the subreddit's top listing
+// across the hour, day and week windows
+func markTrending(stories []Story, subreddit string) error {
+	byWindow, err := fetchStoriesMultiWindow(subreddit, []string{"hour", "day", "week"})
+	if err != nil {
+		return err
+	}
+	trending := trendingLinks(byWindow)
+	for i := range stories {
+		stories[i].Trending = trending[stories[i].Link]
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+)
+
+// DestinationType identifies an external service the bot calls
+type DestinationType string
+
+const (
+	DestinationHuggingFace DestinationType = "huggingface"
+	DestinationSlack       DestinationType = "slack"
+	DestinationZulip       DestinationType = "zulip"
+	DestinationDiscord     DestinationType = "discord"
+	DestinationAPNs        DestinationType = "apns"
+)
+
+// RetryPolicy controls how failed calls to a destination are retried
+type RetryPolicy struct {
+	MaxAttempts          int
+	BackoffBase          time.Duration
+	RetryableStatusCodes []int
+}
+
+// retryPolicies holds the retry policy for each destination. Hugging Face
+// returns 503 while a model loads, so it tolerates many retries. Slack is
+// only retried on rate limiting, which it answers before posting, since a
+// webhook call retried after a server error may post twice
+var retryPolicies = map[DestinationType]RetryPolicy{
+	DestinationHuggingFace: {
+		MaxAttempts:          5,
+		BackoffBase:          2 * time.Second,
+		RetryableStatusCodes: []int{429, 502, 503, 504},
+	},
+	DestinationSlack: {
+		MaxAttempts:          3,
+		BackoffBase:          2 * time.Second,
+		RetryableStatusCodes: []int{429},
+	},
+	DestinationZulip: {
+		MaxAttempts:          3,
+		BackoffBase:          2 * time.Second,
+		RetryableStatusCodes: []int{429, 502, 503, 504},
+	},
+	DestinationDiscord: {
+		MaxAttempts:          3,
+		BackoffBase:          2 * time.Second,
+		RetryableStatusCodes: []int{429, 502, 503, 504},
+	},
+	// APNs answers 500 and 503 when it's briefly unable to deliver
+	DestinationAPNs: {
+		MaxAttempts:          3,
+		BackoffBase:          time.Second,
+		RetryableStatusCodes: []int{429, 500, 503},
+	},
+}
+
+// statusError is returned when a service responds with an unexpected HTTP status
+type statusError struct {
+	Service    string
+	StatusCode int
+	Status     string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s responded with status: %v", e.Service, e.Status)
+}
+
+// retryable reports whether err is worth another attempt under this policy.
+// Responses are retried when their status is one of the policy's; failures
+// without a response fall back to NotifyError's classification
+func (p RetryPolicy) retryable(err error) bool {
+	var ne *NotifyError
+	if errors.As(err, &ne) {
+		if ne.StatusCode == 0 {
+			return ne.Retryable()
+		}
+		return p.retryableStatus(ne.StatusCode)
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return p.retryableStatus(se.StatusCode)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryableStatus reports whether code is one of the policy's retryable statuses
+func (p RetryPolicy) retryableStatus(code int) bool {
+	for _, retryable := range p.RetryableStatusCodes {
+		if code == retryable {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetry calls fn until it succeeds, fails with a non-retryable error, or
+// the destination's attempts are exhausted, backing off exponentially
+func withRetry(dest DestinationType, fn func() error) error {
+	policy := retryPolicies[dest]
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			return err
+		}
+		backoff := policy.BackoffBase << (attempt - 1)
+		log.Printf("%s attempt %d failed (%v), retrying in %s", dest, attempt, err, backoff)
+		time.Sleep(backoff)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, RetryableStatusCodes: []int{429}}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"listed status", &NotifyError{Destination: "slack", StatusCode: 429}, true},
+		{"status Retryable() allows but the policy doesn't list", &NotifyError{Destination: "slack", StatusCode: 502}, false},
+		{"transport failure", &NotifyError{Destination: "slack", Err: errors.New("connection reset")}, true},
+		{"status error", &statusError{Service: "Hugging Face", StatusCode: 429}, true},
+		{"other error", errors.New("bad request"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.retryable(tt.err); got != tt.want {
+				t.Errorf("retryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
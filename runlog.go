@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxStoryLogLines caps the buffered log lines kept per story
+const maxStoryLogLines = 100
+
+// runLog groups log lines by story so concurrent workers don't interleave.
+// In streaming mode lines are written immediately instead
+type runLog struct {
+	mu      sync.Mutex
+	stream  bool
+	order   []string
+	stories map[string]*storyLog
+}
+
+// storyLog holds the buffered lines and failures of one story
+type storyLog struct {
+	mu       sync.Mutex
+	title    string
+	stream   bool
+	lines    []string
+	dropped  int
+	failures []string
+	ctx      *StoryContext
+	finished bool
+}
+
+// newRunLog creates a run log, streaming lines as they happen when stream is set
+func newRunLog(stream bool) *runLog {
+	return &runLog{stream: stream, stories: make(map[string]*storyLog)}
+}
+
+// story returns the log for story, creating it on first use
+func (r *runLog) story(story Story) *storyLog {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := story.Link
+	if l, ok := r.stories[key]; ok {
+		return l
+	}
+	l := &storyLog{title: story.Title, stream: r.stream, ctx: &StoryContext{URL: story.Link}}
+	r.stories[key] = l
+	r.order = append(r.order, key)
+	return l
+}
+
+// Printf records a line for the story
+func (l *storyLog) Printf(format string, args ...interface{}) {
+	line := fmt.Sprintf(format, args...)
+	if l.stream {
+		log.Printf("[%s] %s", l.title, line)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.lines) >= maxStoryLogLines {
+		l.dropped++
+		return
+	}
+	l.lines = append(l.lines, line)
+}
+
+// Fail records the final error of a failed stage
+func (l *storyLog) Fail(stage string, err error) {
+	l.Printf("%s failed: %v", stage, err)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.failures = append(l.failures, fmt.Sprintf("%s: %v", stage, err))
+
+	l.ctx.mu.Lock()
+	l.ctx.failed = true
+	l.ctx.mu.Unlock()
+}
+
+// stage times fn as the named pipeline stage
+func (l *storyLog) stage(name string, fn func() error) error {
+	end := l.ctx.startStage(name)
+	defer end()
+	return fn()
+}
+
+// deliver times a post to destination, recording the destination on success
+func (l *storyLog) deliver(destination string, fn func() error) error {
+	err := l.stage("post:"+destination, fn)
+	if err == nil {
+		l.ctx.mu.Lock()
+		l.ctx.Destinations = append(l.ctx.Destinations, destination)
+		l.ctx.mu.Unlock()
+	}
+	return err
+}
+
+// setInput records the summarizer input in the story's trace
+func (l *storyLog) setInput(input string) {
+	l.ctx.mu.Lock()
+	defer l.ctx.mu.Unlock()
+	l.ctx.Input = input
+}
+
+// setStatus overrides the story's derived final status
+func (l *storyLog) setStatus(status string) {
+	l.ctx.mu.Lock()
+	defer l.ctx.mu.Unlock()
+	l.ctx.Status = status
+}
+
+// finish emits the story's structured completion line. Only the first
+// call for a story emits it
+func (l *storyLog) finish() {
+	l.mu.Lock()
+	finished := l.finished
+	l.finished = true
+	l.mu.Unlock()
+	if !finished {
+		slog.Info("story_processed", "story", l.ctx)
+	}
+}
+
+// runStats totals what happened to the stories in a run
+type runStats struct {
+	Stories       int
+	Failed        int
+	Summarized    int           // stories that called the summarizer
+	SummarizeTime time.Duration // total time spent in the summarize stage
+}
+
+// stats totals the run's stories, failures and summarize stage timings
+func (r *runLog) stats() runStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := runStats{Stories: len(r.order)}
+	for _, key := range r.order {
+		l := r.stories[key]
+		l.mu.Lock()
+		if len(l.failures) > 0 {
+			stats.Failed++
+		}
+		l.mu.Unlock()
+
+		l.ctx.mu.Lock()
+		for _, stage := range l.ctx.Stages {
+			if stage.Name == "summarize" {
+				stats.Summarized++
+				stats.SummarizeTime += stage.End.Sub(stage.Start)
+			}
+		}
+		l.ctx.mu.Unlock()
+	}
+	return stats
+}
+
+// flush writes the buffered lines grouped by story, then a digest of failed
+// stories to stderr. Completion lines are emitted as each story finishes;
+// any story not finished yet gets its line here
+func (r *runLog) flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var failed []*storyLog
+	for _, key := range r.order {
+		l := r.stories[key]
+		l.mu.Lock()
+		if !r.stream && len(l.lines) > 0 {
+			log.Printf("== %s", l.title)
+			for _, line := range l.lines {
+				log.Printf("   %s", line)
+			}
+			if l.dropped > 0 {
+				log.Printf("   ... %d more lines dropped", l.dropped)
+			}
+		}
+		if len(l.failures) > 0 {
+			failed = append(failed, l)
+		}
+		l.mu.Unlock()
+		l.finish()
+	}
+
+	if len(failed) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\n%d of %d stories had failures:\n", len(failed), len(r.order))
+	for _, l := range failed {
+		fmt.Fprintf(os.Stderr, "- %s\n", l.title)
+		for _, failure := range l.failures {
+			fmt.Fprintf(os.Stderr, "    %s\n", failure)
+		}
+	}
+}
+
+// formatRunSummary renders the end-of-run status line, e.g.
+// "✅ Processed 5/5 stories in 23s | HF avg latency: 12s | 0 errors"
+func formatRunSummary(posted, total int, elapsed time.Duration, stats runStats) string {
+	icon := "✅"
+	if stats.Failed > 0 {
+		icon = "⚠️"
+	}
+	latency := "n/a"
+	if stats.Summarized > 0 {
+		latency = (stats.SummarizeTime / time.Duration(stats.Summarized)).Round(time.Second).String()
+	}
+	errors := "errors"
+	if stats.Failed == 1 {
+		errors = "error"
+	}
+	return fmt.Sprintf("%s Processed %d/%d stories in %s | HF avg latency: %s | %d %s",
+		icon, posted, total, elapsed.Round(time.Second), latency, stats.Failed, errors)
+}
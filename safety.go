@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// defaultSafetyPatterns flag graphic detail that doesn't belong in a general
+// work channel
+var defaultSafetyPatterns = []string{
+	`(?i)\b(decapitat|dismember|disembowel|mutilat)\w*`,
+	`(?i)\bgraphic (video|footage|images?)\b`,
+	`(?i)\b(bodies|body parts) (were|was) (found|strewn|scattered)\b`,
+	`(?i)\bblood[- ]soaked\b`,
+	`(?i)\b(tortured|burned|beaten) (to death|alive)\b`,
+}
+
+// contentFilter is the safety filter applied to summaries, or nil when disabled
+var contentFilter *SafetyFilter
+
+// SafetyFilter flags summaries matching any of its patterns
+type SafetyFilter struct {
+	patterns []*regexp.Regexp
+}
+
+// newSafetyFilter compiles the built-in patterns plus any read from
+// blocklistFile (one regular expression per line, # for comments)
+func newSafetyFilter(blocklistFile string) (*SafetyFilter, error) {
+	sources := append([]string(nil), defaultSafetyPatterns...)
+	if blocklistFile != "" {
+		f, err := os.Open(blocklistFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				sources = append(sources, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	filter := &SafetyFilter{}
+	for _, source := range sources {
+		re, err := regexp.Compile(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid safety pattern %q: %w", source, err)
+		}
+		filter.patterns = append(filter.patterns, re)
+	}
+	return filter, nil
+}
+
+// Check returns the first pattern matching text, if any
+func (f *SafetyFilter) Check(text string) (string, bool) {
+	for _, re := range f.patterns {
+		if re.MatchString(text) {
+			return re.String(), true
+		}
+	}
+	return "", false
+}
+
+// applySafetyFilter replaces a flagged summary with a neutral note
+func applySafetyFilter(story Story, summary string, logs *storyLog) string {
+	if contentFilter == nil {
+		return summary
+	}
+	pattern, flagged := contentFilter.Check(summary)
+	if !flagged {
+		return summary
+	}
+	logs.Printf("safety_filter: withheld summary (matched %s)", pattern)
+	return fmt.Sprintf("Summary withheld as it may contain graphic details — details at the link: %s", story.Link)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplySafetyFilter(t *testing.T) {
+	blocklist := filepath.Join(t.TempDir(), "blocklist.txt")
+	err := os.WriteFile(blocklist, []byte("# local additions\n\n(?i)\\bmassacre\\b\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter, err := newSafetyFilter(blocklist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := contentFilter
+	contentFilter = filter
+	t.Cleanup(func() { contentFilter = previous })
+
+	story := Story{Title: "Police investigate", Link: "https://example.com/story"}
+	withheld := "Summary withheld as it may contain graphic details — details at the link: https://example.com/story"
+	tests := []struct {
+		name, summary, want string
+	}{
+		{"clean", "The council approved new bike lanes downtown.", "The council approved new bike lanes downtown."},
+		{"clean despite a similar word", "The footage was widely shared online.", "The footage was widely shared online."},
+		{"built-in pattern", "Police released graphic footage of the attack.", withheld},
+		{"built-in pattern, any case", "Victims were DISMEMBERED, officials said.", withheld},
+		{"built-in phrase", "Residents said bodies were found near the river.", withheld},
+		{"blocklist file pattern", "Witnesses described a Massacre at the market.", withheld},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := newRunLog(false).story(story)
+			if got := applySafetyFilter(story, tt.summary, logs); got != tt.want {
+				t.Errorf("applySafetyFilter(%q) = %q, want %q", tt.summary, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSafetyFilterInvalidPattern(t *testing.T) {
+	blocklist := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(blocklist, []byte("(unclosed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newSafetyFilter(blocklist); err == nil {
+		t.Error("newSafetyFilter accepted an invalid pattern")
+	}
+}
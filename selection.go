@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// selectionStage is one of the filters candidate stories go through before
+// the top few are summarized
+type selectionStage struct {
+	name  string // as in "dropped by the <name> filter"
+	apply func([]Story) []Story
+	// explain describes the values the filter compares for story, given the
+	// stories the filter was applied to
+	explain func(story Story, input []Story) string
+}
+
+// selectionStages returns the run's story filters as configured, in the
+// order they apply. cutoff is the --since cutoff (zero for none) and store
+// may be nil. Nothing is written to the store
+func selectionStages(cutoff time.Time, store Store) []selectionStage {
+	stages := []selectionStage{{
+		name:  "--since cutoff",
+		apply: func(stories []Story) []Story { return publishedSince(stories, cutoff) },
+		explain: func(story Story, _ []Story) string {
+			switch {
+			case cutoff.IsZero():
+				return "no cutoff"
+			case story.Published.IsZero():
+				return "no published time, kept"
+			case story.Published.Before(cutoff):
+				return fmt.Sprintf("published %s < cutoff %s", explainTime(story.Published), explainTime(cutoff))
+			}
+			return fmt.Sprintf("published %s ≥ cutoff %s", explainTime(story.Published), explainTime(cutoff))
+		},
+	}}
+	if os.Getenv("SKIP_IMAGE_POSTS") == "true" {
+		stages = append(stages, selectionStage{
+			name:  "SKIP_IMAGE_POSTS",
+			apply: filterImagePosts,
+			explain: func(story Story, _ []Story) string {
+				if story.ImagePost {
+					return fmt.Sprintf("%s is an image", articleLink(story))
+				}
+				return fmt.Sprintf("%s is not an image", articleLink(story))
+			},
+		})
+	}
+	threshold := titleDedupThreshold()
+	stages = append(stages, selectionStage{
+		name: "duplicate title",
+		apply: func(stories []Story) []Story {
+			return dedupeTitles(stories, threshold, priorityDomainsFromEnv())
+		},
+		explain: func(story Story, input []Story) string {
+			closest, similarity := "", 0.0
+			for _, other := range input {
+				if other.Link == story.Link {
+					continue
+				}
+				if s := levenshteinSimilarity(strings.ToLower(story.Title), strings.ToLower(other.Title)); s > similarity {
+					closest, similarity = other.Title, s
+				}
+			}
+			if closest == "" {
+				return "no other titles to compare"
+			}
+			op := "≤"
+			if similarity > threshold {
+				op = ">"
+			}
+			return fmt.Sprintf("similarity %.2f to %q %s TITLE_DEDUP_THRESHOLD %.2f", similarity, closest, op, threshold)
+		},
+	})
+	if store == nil {
+		return stages
+	}
+	cooldownDays := envInt("STORY_REPOST_COOLDOWN_DAYS", 7)
+	cooldown := time.Duration(cooldownDays) * 24 * time.Hour
+	stages = append(stages, selectionStage{
+		name:  "repost cooldown",
+		apply: func(stories []Story) []Story { return filterRecentlyPosted(stories, store, cooldown) },
+		explain: func(story Story, _ []Story) string {
+			lastPosted, ok, err := store.LastPostedAt(story.Link)
+			if err != nil {
+				return fmt.Sprintf("story store lookup failed: %v", err)
+			}
+			if !ok {
+				return "not in the story store"
+			}
+			ago := time.Since(lastPosted)
+			op := "≥"
+			if ago < cooldown {
+				op = "<"
+			}
+			return fmt.Sprintf("in the story store, last posted %s, %.1f days ago %s STORY_REPOST_COOLDOWN_DAYS %d", explainTime(lastPosted), ago.Hours()/24, op, cooldownDays)
+		},
+	})
+	if maxPerAuthor := envInt("SPAM_MAX_POSTS_PER_AUTHOR_PER_DAY", 0); maxPerAuthor > 0 {
+		stages = append(stages, selectionStage{
+			name:  "SPAM_MAX_POSTS_PER_AUTHOR_PER_DAY",
+			apply: func(stories []Story) []Story { return filterProlificAuthors(stories, store, maxPerAuthor) },
+			explain: func(story Story, input []Story) string {
+				if !knownAuthor(story.Author) {
+					return "no known author"
+				}
+				posted, err := checkAuthorFrequency(story.Author, 24*time.Hour, store)
+				if err != nil {
+					return fmt.Sprintf("story store lookup failed: %v", err)
+				}
+				// Stories by the same author kept earlier in the run count too
+				earlier := 0
+				for _, other := range input {
+					if other.Link == story.Link {
+						break
+					}
+					if other.Author == story.Author && posted+earlier+1 <= maxPerAuthor {
+						earlier++
+					}
+				}
+				total := posted + earlier + 1
+				op := "≤"
+				if total > maxPerAuthor {
+					op = ">"
+				}
+				return fmt.Sprintf("%s would have %d posts in a day (%d posted, %d earlier in this run) %s limit %d", story.Author, total, posted, earlier, op, maxPerAuthor)
+			},
+		})
+	}
+	return stages
+}
+
+// explainTime formats t for filter explanations, in the bot's timezone
+// when it's valid
+func explainTime(t time.Time) string {
+	if loc, err := botLocation(); err == nil {
+		t = t.In(loc)
+	}
+	return t.Format("2006-01-02 15:04 MST")
+}
+
+// selectStories tags image posts and runs stories through stages, calling
+// observe (when not nil) with each stage, the stories it was applied to and
+// the ones it kept. The survivors are then ranked by PRIORITY_DOMAINS,
+// which can only reorder stories the stages kept
+func selectStories(stories []Story, stages []selectionStage, observe func(stage selectionStage, input, kept []Story)) []Story {
+	markImagePosts(stories)
+	for _, stage := range stages {
+		kept := stage.apply(stories)
+		if observe != nil {
+			observe(stage, stories, kept)
+		}
+		stories = kept
+	}
+	return orderByPriority(stories, priorityDomainsFromEnv())
+}
+
+// containsLink reports whether stories include one linking to link
+func containsLink(stories []Story, link string) bool {
+	for _, story := range stories {
+		if story.Link == link {
+			return true
+		}
+	}
+	return false
+}
+
+// publishedSince drops stories published before cutoff. Stories without a
+// published time are kept
+func publishedSince(stories []Story, cutoff time.Time) []Story {
+	if cutoff.IsZero() {
+		return stories
+	}
+	var kept []Story
+	for _, story := range stories {
+		if story.Published.IsZero() || !story.Published.Before(cutoff) {
+			kept = append(kept, story)
+		}
+	}
+	return kept
+}
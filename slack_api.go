@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// slackAPIBase is the Slack Web API root
+const slackAPIBase = "https://slack.com/api/"
+
+// slackAPIResponse holds the fields common to every Slack Web API response
+type slackAPIResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+// callSlackAPI calls a Slack Web API method with bot token auth and decodes
+// the response into out
+func callSlackAPI(token, method string, form url.Values, out interface{}) error {
+	return withRetry(DestinationSlack, func() error {
+		req, err := http.NewRequest("POST", slackAPIBase+method, strings.NewReader(form.Encode()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		client := &http.Client{Timeout: 15 * time.Second}
+		resp, err := client.Do(req)
+		if err != nil {
+			return &NotifyError{Destination: "slack " + method, Err: err}
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != 200 {
+			return notifyResponseError("slack "+method, resp)
+		}
+
+		var raw json.RawMessage
+		if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+			return err
+		}
+		var base slackAPIResponse
+		if err := json.Unmarshal(raw, &base); err != nil {
+			return err
+		}
+		if !base.OK {
+			return &NotifyError{Destination: "slack " + method, StatusCode: resp.StatusCode, Body: base.Error}
+		}
+		if out != nil {
+			return json.Unmarshal(raw, out)
+		}
+		return nil
+	})
+}
+
+// postSlackMessage posts text to channel with chat.postMessage and returns
+// the message timestamp. iconURL, if set, replaces the bot's avatar and
+// needs the chat:write.customize scope
+func postSlackMessage(token, channel, text, iconURL string) (string, error) {
+	var result struct {
+		TS string `json:"ts"`
+	}
+	form := url.Values{
+		"channel": {channel},
+		"text":    {text},
+	}
+	if iconURL != "" {
+		form.Set("icon_url", iconURL)
+	}
+	err := callSlackAPI(token, "chat.postMessage", form, &result)
+	return result.TS, err
+}
+
+// pinSlackMessage pins the message at ts, first unpinning the bot's
+// previous pin (previousTS) if it is still pinned
+func pinSlackMessage(token, channel, ts, previousTS string) error {
+	if previousTS != "" {
+		var pins struct {
+			Items []struct {
+				Message struct {
+					TS string `json:"ts"`
+				} `json:"message"`
+			} `json:"items"`
+		}
+		if err := callSlackAPI(token, "pins.list", url.Values{"channel": {channel}}, &pins); err != nil {
+			return err
+		}
+		for _, item := range pins.Items {
+			if item.Message.TS != previousTS {
+				continue
+			}
+			err := callSlackAPI(token, "pins.remove", url.Values{
+				"channel":   {channel},
+				"timestamp": {previousTS},
+			}, nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return callSlackAPI(token, "pins.add", url.Values{
+		"channel":   {channel},
+		"timestamp": {ts},
+	}, nil)
+}
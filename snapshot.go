@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotTimeFormat timestamps snapshot filenames so they sort chronologically
+const snapshotTimeFormat = "20060102T150405Z"
+
+// snapshotExtensions maps each listing format to its snapshot file suffix
+var snapshotExtensions = map[listingFormat]string{
+	formatRSS:      "-rss.xml",
+	formatJSONFeed: "-jsonfeed.json",
+	formatReddit:   "-reddit.json",
+}
+
+// saveSnapshot writes the raw fetched listing to SNAPSHOT_DIR (when set)
+// and prunes old snapshots. Failures are logged, never fatal
+func saveSnapshot(data []byte, format listingFormat) {
+	dir := os.Getenv("SNAPSHOT_DIR")
+	if dir == "" {
+		return
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Printf("Error creating snapshot directory: %v", err)
+		return
+	}
+	name := time.Now().UTC().Format(snapshotTimeFormat) + snapshotExtensions[format]
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		log.Printf("Error saving feed snapshot: %v", err)
+		return
+	}
+	log.Printf("Saved feed snapshot: %s", path)
+
+	maxAge := time.Duration(envInt("SNAPSHOT_MAX_AGE_DAYS", 14)) * 24 * time.Hour
+	pruneSnapshots(dir, envInt("SNAPSHOT_MAX_COUNT", 100), maxAge)
+}
+
+// pruneSnapshots keeps at most maxCount snapshots in dir, deleting any older than maxAge
+func pruneSnapshots(dir string, maxCount int, maxAge time.Duration) {
+	var snapshots []string
+	for _, ext := range snapshotExtensions {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*"+ext))
+		snapshots = append(snapshots, matches...)
+	}
+	// Newest first
+	sort.Sort(sort.Reverse(sort.StringSlice(snapshots)))
+
+	for i, path := range snapshots {
+		taken, err := snapshotTime(path)
+		if err != nil {
+			continue
+		}
+		if i >= maxCount || time.Since(taken) > maxAge {
+			if err := os.Remove(path); err != nil {
+				log.Printf("Error pruning snapshot %s: %v", path, err)
+			}
+		}
+	}
+}
+
+// snapshotTime parses the timestamp from a snapshot filename
+func snapshotTime(path string) (time.Time, error) {
+	name := filepath.Base(path)
+	if len(name) < len(snapshotTimeFormat) {
+		return time.Time{}, fmt.Errorf("not a snapshot: %s", name)
+	}
+	return time.Parse(snapshotTimeFormat, name[:len(snapshotTimeFormat)])
+}
+
+// readSnapshot loads a saved listing, inferring its format from the filename
+func readSnapshot(path string) ([]byte, listingFormat, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	for format, ext := range snapshotExtensions {
+		if strings.HasSuffix(path, ext) {
+			return data, format, nil
+		}
+	}
+	return data, formatRSS, nil
+}
+
+// runReplay implements --replay: it reproduces a run's story selection from
+// a saved snapshot and prints each decision. It's a dry run, so nothing is
+// summarized or posted and neither the state file nor the store is written
+func runReplay(path, since string) {
+	state, err := loadState(stateFilePath())
+	if err != nil {
+		log.Fatalf("Error loading state file: %v", err)
+	}
+	cutoff, err := resolveSince(since, state.LastRunAt)
+	if err != nil {
+		log.Fatalf("Invalid --since value: %v", err)
+	}
+	store, err := openStore()
+	if err != nil {
+		log.Fatalf("Failed to open story store: %v", err)
+	}
+	stories, err := fetchTopStories(path)
+	if err != nil {
+		log.Fatalf("Failed to read snapshot: %v", err)
+	}
+
+	fmt.Printf("Replaying %d stories from %s\n", len(stories), path)
+	// Pending pins go at the top of the digest as in a run, without being
+	// cleared
+	pruneExpiredPins(&state, pinExpiry())
+	for _, pin := range pinnedStories(state.Pins) {
+		fmt.Printf("✓ %s — pinned, would go at the top of the digest\n", pin.Title)
+	}
+	selected := selectStories(stories, selectionStages(cutoff, store), func(stage selectionStage, input, kept []Story) {
+		for _, story := range input {
+			if !containsLink(kept, story.Link) {
+				fmt.Printf("✗ %s — dropped by the %s filter: %s\n", story.Title, stage.name, stage.explain(story, input))
+			}
+		}
+	})
+	limit := runSummaryLimit(selected)
+	for rank, story := range selected {
+		if rank >= limit {
+			fmt.Printf("✗ %s — rank %d is past the summary limit %d\n", story.Title, rank+1, limit)
+			continue
+		}
+		fmt.Printf("✓ %s — rank %d, would be summarized and posted\n", story.Title, rank+1)
+		if note := priorityNote(story, priorityDomainsFromEnv()); note != "" {
+			fmt.Printf("  %s\n", note)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// stageSpan is the timing of one pipeline stage for a story
+type stageSpan struct {
+	Name       string
+	Start, End time.Time
+}
+
+// StoryContext records what happened to a story as it moved through the
+// pipeline, for the structured log line emitted when it completes
+type StoryContext struct {
+	mu           sync.Mutex
+	URL          string
+	Status       string // overrides the derived status when set
+	Input        string // summarizer input, truncated
+	Stages       []stageSpan
+	Destinations []string
+	failed       bool
+}
+
+// startStage begins timing a stage and returns the function that ends it
+func (c *StoryContext) startStage(name string) func() {
+	start := time.Now()
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.Stages = append(c.Stages, stageSpan{Name: name, Start: start, End: time.Now()})
+	}
+}
+
+// status returns the explicit status, or derives one from the outcome:
+// success once posted anywhere, failed after an error, otherwise filtered
+func (c *StoryContext) status() string {
+	switch {
+	case c.Status != "":
+		return c.Status
+	case len(c.Destinations) > 0:
+		return "success"
+	case c.failed:
+		return "failed"
+	}
+	return "filtered"
+}
+
+// LogValue implements slog.LogValuer
+func (c *StoryContext) LogValue() slog.Value {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stages := make([]slog.Attr, 0, len(c.Stages))
+	for _, stage := range c.Stages {
+		stages = append(stages, slog.Duration(stage.Name, stage.End.Sub(stage.Start)))
+	}
+	return slog.GroupValue(
+		slog.String("url", c.URL),
+		slog.String("status", c.status()),
+		slog.Any("destinations", c.Destinations),
+		slog.String("input", c.Input),
+		slog.Attr{Key: "stages", Value: slog.GroupValue(stages...)},
+	)
+}
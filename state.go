@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+// defaultStateFile is where run state is persisted between invocations
+const defaultStateFile = ".newsbot-state.json"
+
+// State holds data persisted between bot runs
+type State struct {
+	LastRunAt  time.Time         `json:"last_run_at,omitempty"`
+	ConfigHash string            `json:"config_hash,omitempty"`
+	Config     map[string]string `json:"config,omitempty"`
+	Pins       []PinnedStory     `json:"pins,omitempty"`
+	PinnedTS   string            `json:"pinned_ts,omitempty"`
+
+	// PendingPosts are summaries held back by the posting window
+	PendingPosts []PendingPost `json:"pending_posts,omitempty"`
+
+	// FailedPosts are Slack posts that failed, to be retried on the next run
+	FailedPosts []FailedPost `json:"failed_posts,omitempty"`
+
+	// Queued are stories held per destination during its quiet hours
+	Queued map[string][]QueuedPost `json:"queued,omitempty"`
+
+	// StoryCounts is the rolling history of new stories fetched per run
+	StoryCounts []int `json:"story_counts,omitempty"`
+
+	// VelocityAlerts maps recently alerted fast-rising stories to when they were alerted
+	VelocityAlerts map[string]time.Time `json:"velocity_alerts,omitempty"`
+}
+
+// stateFilePath returns the configured state file location
+func stateFilePath() string {
+	if path := os.Getenv("STATE_FILE"); path != "" {
+		return path
+	}
+	return defaultStateFile
+}
+
+// loadState reads the state file, returning an empty state if it doesn't exist
+func loadState(path string) (State, error) {
+	var state State
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+// saveState writes the state file atomically via a temporary file
+func saveState(path string, state State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
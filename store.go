@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store records which stories have already been posted, and when
+type Store interface {
+	HasStory(url string) (bool, error)
+	SaveStory(url string) error
+	LastPostedAt(url string) (time.Time, bool, error)
+	RecordAuthorPost(author, url string) error
+	CountAuthorPosts(author string, since time.Time) (int, error)
+}
+
+// postedStoriesKey is the Redis sorted set of posted story URLs, scored by
+// the Unix time they were last posted
+const postedStoriesKey = "newsbot:posted"
+
+// legacySeenStoriesKey is the plain set of posted URLs kept before posting
+// times were recorded. Its stories are carried over by migrateSeenSet
+const legacySeenStoriesKey = "newsbot:seen"
+
+// authorPostsKeyPrefix prefixes the per-author sorted sets of posted URLs
+const authorPostsKeyPrefix = "newsbot:author:"
+
+// RedisStore is a Store backed by a Redis sorted set, shared across bot instances
+type RedisStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisStore connects to the Redis server at redisURL
+func NewRedisStore(redisURL string, ttl time.Duration) (*RedisStore, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &RedisStore{client: client, ttl: ttl}, nil
+}
+
+// HasStory reports whether url has already been posted
+func (r *RedisStore) HasStory(url string) (bool, error) {
+	_, ok, err := r.LastPostedAt(url)
+	return ok, err
+}
+
+// SaveStory records url as posted now and pushes the set's expiry forward
+func (r *RedisStore) SaveStory(url string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	now := time.Now()
+	pipe := r.client.TxPipeline()
+	pipe.ZAdd(ctx, postedStoriesKey, redis.Z{Score: float64(now.Unix()), Member: url})
+	pipe.ZRemRangeByScore(ctx, postedStoriesKey, "-inf", strconv.FormatInt(now.Add(-r.ttl).Unix(), 10))
+	pipe.ExpireAt(ctx, postedStoriesKey, now.Add(r.ttl))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// LastPostedAt returns when url was last posted, if ever
+func (r *RedisStore) LastPostedAt(url string) (time.Time, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	score, err := r.client.ZScore(ctx, postedStoriesKey, url).Result()
+	if err == redis.Nil {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.Unix(int64(score), 0), true, nil
+}
+
+// RecordAuthorPost records that a story by author was posted now
+func (r *RedisStore) RecordAuthorPost(author, url string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	key := authorPostsKeyPrefix + author
+	now := time.Now()
+	pipe := r.client.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.Unix()), Member: url})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-r.ttl).Unix(), 10))
+	pipe.ExpireAt(ctx, key, now.Add(r.ttl))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// CountAuthorPosts returns how many stories by author were posted since the given time
+func (r *RedisStore) CountAuthorPosts(author string, since time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	count, err := r.client.ZCount(ctx, authorPostsKeyPrefix+author, strconv.FormatInt(since.Unix(), 10), "+inf").Result()
+	return int(count), err
+}
+
+// migrateSeenSet moves the stories in the legacy seen set into the posted
+// stories set, then deletes it. Their posting times weren't recorded, so
+// they count as posted at the migration, keeping them within the repost
+// cooldown. Stories already in the posted set keep their times
+func (r *RedisStore) migrateSeenSet() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	kind, err := r.client.Type(ctx, legacySeenStoriesKey).Result()
+	if err != nil || kind != "set" {
+		return 0, err
+	}
+	urls, err := r.client.SMembers(ctx, legacySeenStoriesKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	pipe := r.client.TxPipeline()
+	for _, url := range urls {
+		pipe.ZAddNX(ctx, postedStoriesKey, redis.Z{Score: float64(now.Unix()), Member: url})
+	}
+	pipe.ExpireAt(ctx, postedStoriesKey, now.Add(r.ttl))
+	pipe.Del(ctx, legacySeenStoriesKey)
+	_, err = pipe.Exec(ctx)
+	return len(urls), err
+}
+
+// Close releases the Redis connection
+func (r *RedisStore) Close() error {
+	return r.client.Close()
+}
+
+// openStore returns the configured Store, or nil when none is configured.
+// REDIS_TTL_HOURS must cover STORY_REPOST_COOLDOWN_DAYS, or stories would
+// be forgotten, and reposted, before their cooldown ends
+func openStore() (Store, error) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		return nil, nil
+	}
+	ttlHours := envInt("REDIS_TTL_HOURS", 720)
+	if cooldownDays := envInt("STORY_REPOST_COOLDOWN_DAYS", 7); cooldownDays*24 > ttlHours {
+		return nil, fmt.Errorf("STORY_REPOST_COOLDOWN_DAYS=%d (%d hours) is longer than REDIS_TTL_HOURS=%d", cooldownDays, cooldownDays*24, ttlHours)
+	}
+	return NewRedisStore(redisURL, time.Duration(ttlHours)*time.Hour)
+}
+
+// filterRecentlyPosted drops stories posted within the cooldown window and
+// marks stories posted before it as revisited
+func filterRecentlyPosted(stories []Story, store Store, cooldown time.Duration) []Story {
+	var eligible []Story
+	for _, story := range stories {
+		lastPosted, ok, err := store.LastPostedAt(story.Link)
+		if err != nil {
+			log.Printf("Error checking store for '%s': %v", story.Title, err)
+		}
+		if ok {
+			if time.Since(lastPosted) < cooldown {
+				continue
+			}
+			story.Revisited = true
+		}
+		eligible = append(eligible, story)
+	}
+	return eligible
+}
+
+// checkAuthorFrequency returns how many stories by author were posted within window
+func checkAuthorFrequency(author string, window time.Duration, store Store) (int, error) {
+	return store.CountAuthorPosts(author, time.Now().Add(-window))
+}
+
+// filterProlificAuthors drops stories that would give their author more
+// than maxPerDay posts in 24 hours, counting the author's posts in the store
+// and their stories kept earlier in this run
+func filterProlificAuthors(stories []Story, store Store, maxPerDay int) []Story {
+	var kept []Story
+	keptByAuthor := make(map[string]int)
+	for _, story := range stories {
+		if knownAuthor(story.Author) {
+			count, err := checkAuthorFrequency(story.Author, 24*time.Hour, store)
+			if err != nil {
+				log.Printf("Error checking post frequency for %s: %v", story.Author, err)
+			}
+			count += keptByAuthor[story.Author]
+			if count+1 > maxPerDay {
+				log.Printf("Warning: skipping '%s' — %s already has %d posts in the last day", story.Title, story.Author, count)
+				continue
+			}
+			keptByAuthor[story.Author]++
+		}
+		kept = append(kept, story)
+	}
+	return kept
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// minUserAgents is the smallest user-agent pool worth rotating through
+const minUserAgents = 3
+
+// userAgents rotates the User-Agent of story fetches when USER_AGENTS_FILE
+// is set
+var userAgents *UserAgentRotator
+
+// fetchUserAgent is the User-Agent sent with feed and listing requests
+var fetchUserAgent = redditUserAgent
+
+// UserAgentRotator hands out user agents round-robin
+type UserAgentRotator struct {
+	agents []string
+	next   atomic.Uint64
+}
+
+// NewUserAgentRotator creates a rotator over agents. It starts at a random
+// entry, since each run usually fetches only once
+func NewUserAgentRotator(agents []string) *UserAgentRotator {
+	r := &UserAgentRotator{agents: agents}
+	r.next.Store(rand.Uint64N(uint64(len(agents))))
+	return r
+}
+
+// Next returns the next user agent in the pool
+func (r *UserAgentRotator) Next() string {
+	n := r.next.Add(1) - 1
+	return r.agents[n%uint64(len(r.agents))]
+}
+
+// loadUserAgents reads one user agent per line from path, skipping blank
+// lines and # comments, and requires at least minUserAgents of them
+func loadUserAgents(path string) (*UserAgentRotator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var agents []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		agents = append(agents, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(agents) < minUserAgents {
+		return nil, fmt.Errorf("%s has %d user agents, need at least %d", path, len(agents), minUserAgents)
+	}
+	return NewUserAgentRotator(agents), nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// velocityAlertMemory is how long an alerted story is remembered, so it
+// isn't alerted again on every run while it stays hot
+const velocityAlertMemory = 48 * time.Hour
+
+// computeVelocity returns a story's upvotes per hour since it was created.
+// Stories under an hour old count as an hour old, so a fresh post with a
+// handful of votes doesn't look explosive
+func computeVelocity(currentScore int, createdAt time.Time) float64 {
+	hours := time.Since(createdAt).Hours()
+	if hours < 1 {
+		hours = 1
+	}
+	return float64(currentScore) / hours
+}
+
+// velocityAlertThreshold reads VELOCITY_ALERT_THRESHOLD in upvotes per hour,
+// reporting whether alerts are enabled
+func velocityAlertThreshold() (float64, bool) {
+	threshold, err := strconv.ParseFloat(os.Getenv("VELOCITY_ALERT_THRESHOLD"), 64)
+	if err != nil || threshold <= 0 {
+		return 0, false
+	}
+	return threshold, true
+}
+
+// alertFastStories posts every candidate gaining votes faster than threshold
+// to ALERT_SLACK_WEBHOOK_URL, whether or not it makes this run's batch.
+// Stories without a score or publish time are skipped
+func alertFastStories(state *State, stories []Story, threshold float64) {
+	alertWebhook := os.Getenv("ALERT_SLACK_WEBHOOK_URL")
+	if alertWebhook == "" {
+		log.Println("VELOCITY_ALERT_THRESHOLD requires ALERT_SLACK_WEBHOOK_URL — not alerting.")
+		return
+	}
+	for link, alertedAt := range state.VelocityAlerts {
+		if time.Since(alertedAt) > velocityAlertMemory {
+			delete(state.VelocityAlerts, link)
+		}
+	}
+	for _, story := range stories {
+		if story.Score == 0 || story.Published.IsZero() {
+			continue
+		}
+		if _, alerted := state.VelocityAlerts[story.Link]; alerted {
+			continue
+		}
+		velocity := computeVelocity(story.Score, story.Published)
+		if velocity <= threshold {
+			continue
+		}
+		message := fmt.Sprintf("🚀 Fast-rising story: %s (%d upvotes, %.0f/hour)\n%s", story.Title, story.Score, velocity, story.Link)
+		log.Println(message)
+		if err := postToSlack(alertWebhook, message); err != nil {
+			log.Printf("Error posting velocity alert to Slack: %v", err)
+			continue
+		}
+		if state.VelocityAlerts == nil {
+			state.VelocityAlerts = make(map[string]time.Time)
+		}
+		state.VelocityAlerts[story.Link] = time.Now()
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ZulipConfig holds the bot credentials and destination for Zulip posts
+type ZulipConfig struct {
+	BotEmail string
+	APIKey   string
+	Realm    string
+	Stream   string
+	Topic    string
+}
+
+// zulipConfigFromEnv reads the Zulip settings, reporting whether all of
+// them are present
+func zulipConfigFromEnv() (ZulipConfig, bool) {
+	cfg := ZulipConfig{
+		BotEmail: os.Getenv("ZULIP_BOT_EMAIL"),
+		APIKey:   os.Getenv("ZULIP_API_KEY"),
+		Realm:    os.Getenv("ZULIP_REALM"),
+		Stream:   os.Getenv("ZULIP_STREAM"),
+		Topic:    os.Getenv("ZULIP_TOPIC"),
+	}
+	ok := cfg.BotEmail != "" && cfg.APIKey != "" && cfg.Realm != "" && cfg.Stream != "" && cfg.Topic != ""
+	return cfg, ok
+}
+
+// post sends content to the configured stream and topic
+func (c ZulipConfig) post(content string) error {
+	return postToZulip(c.BotEmail, c.APIKey, c.Realm, c.Stream, c.Topic, content)
+}
+
+// formatZulipMessage formats a story using Zulip's Markdown
+func formatZulipMessage(story Story, summary string) string {
+	if summary == story.Title {
+		return fmt.Sprintf("**%s**", displayTitle(story))
+	}
+	return fmt.Sprintf("**%s**\n> %s", displayTitle(story), summary)
+}
+
+// postToZulip sends a stream message through the Zulip API, authenticating
+// with the bot's email and API key
+func postToZulip(botEmail, apiKey, realm, stream, topic, content string) error {
+	realm = strings.TrimPrefix(strings.TrimPrefix(realm, "https://"), "http://")
+	endpoint := "https://" + strings.TrimRight(realm, "/") + "/api/v1/messages"
+
+	form := url.Values{
+		"type":    {"stream"},
+		"to":      {stream},
+		"topic":   {topic},
+		"content": {content},
+	}
+	return withRetry(DestinationZulip, func() error {
+		req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(botEmail, apiKey)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		client := &http.Client{Timeout: 15 * time.Second}
+		resp, err := client.Do(req)
+		if err != nil {
+			return &NotifyError{Destination: "zulip", Err: err}
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != 200 {
+			return notifyResponseError("zulip", resp)
+		}
+		return nil
+	})
+}